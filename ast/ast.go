@@ -2,12 +2,16 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"monkeylang/token"
+	"strings"
 )
 
 type Node interface {
     TokenLiteral() string
     String() string
+    Pos() token.Position // First character of the node
+    End() token.Position // Just past the last character of the node
 }
 
 type Statement interface {
@@ -23,6 +27,7 @@ type Expression interface {
 // Implements the Node interface
 type Program struct {
     Statements []Statement
+    Comments []token.Comment // All comments of the source, in order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -32,6 +37,20 @@ func (p *Program) TokenLiteral() string {
     return ""
 }
 
+func (p *Program) Pos() token.Position {
+    if len(p.Statements) > 0 {
+        return p.Statements[0].Pos()
+    }
+    return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+    if len(p.Statements) > 0 {
+        return p.Statements[len(p.Statements)-1].End()
+    }
+    return token.Position{}
+}
+
 func (p *Program) String() string {
     var out bytes.Buffer
     for _, s := range p.Statements {
@@ -50,6 +69,16 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+    if ls.Value != nil {
+        return ls.Value.End()
+    }
+    if ls.Name != nil {
+        return ls.Name.End()
+    }
+    return ls.Token.End
+}
 func (ls *LetStatement) String() string { 
     var out bytes.Buffer
     out.WriteString(ls.TokenLiteral() + " ")
@@ -71,12 +100,19 @@ type ReturnStatement struct {
 }
 func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+    if rs.ReturnValue != nil {
+        return rs.ReturnValue.End()
+    }
+    return rs.Token.End
+}
 func (rs *ReturnStatement) String() string {
     var out bytes.Buffer
-    out.WriteString(rs.TokenLiteral() + " ")
+    out.WriteString(rs.TokenLiteral())
 
     if rs.ReturnValue != nil {
-        out.WriteString(rs.ReturnValue.String())
+        out.WriteString(" " + rs.ReturnValue.String())
     }
 
     out.WriteString(";")
@@ -91,6 +127,8 @@ type Identifier struct {
 }
 func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
 func (i *Identifier) String() string { return i.Value }
 
 
@@ -101,6 +139,13 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+    if es.Expression != nil {
+        return es.Expression.End()
+    }
+    return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
     if es.Expression != nil {
         return es.Expression.String()
@@ -115,8 +160,20 @@ type IntegerLiteral struct {
 }
 func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
 func (il *IntegerLiteral) String() string { return il.Token.Literal}
 
+type FloatLiteral struct {
+    Token token.Token
+    Value float64
+}
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position { return fl.Token.End }
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 type PrefixExpression struct {
     Token token.Token 
     Operator string
@@ -125,6 +182,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {return pe.Token.Literal}
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+    if pe.Right != nil {
+        return pe.Right.End()
+    }
+    return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
     var out bytes.Buffer
     out.WriteString("(")
@@ -144,6 +208,18 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode() {}
 func (ie *InfixExpression) TokenLiteral() string {return ie.Token.Literal}
+func (ie *InfixExpression) Pos() token.Position {
+    if ie.Left != nil {
+        return ie.Left.Pos()
+    }
+    return ie.Token.Pos
+}
+func (ie *InfixExpression) End() token.Position {
+    if ie.Right != nil {
+        return ie.Right.End()
+    }
+    return ie.Token.End
+}
 func (ie *InfixExpression) String() string {
     var out bytes.Buffer
     out.WriteString("(")
@@ -154,3 +230,449 @@ func (ie *InfixExpression) String() string {
 
     return out.String()
 }
+
+type Boolean struct {
+    Token token.Token
+    Value bool
+}
+
+func (b *Boolean) expressionNode() {}
+func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End }
+func (b *Boolean) String() string { return b.Token.Literal }
+
+type BlockStatement struct {
+    Token token.Token // The { token
+    Statements []Statement
+    RBrace token.Token
+}
+
+func (bs *BlockStatement) statementNode() {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position { return bs.RBrace.End }
+func (bs *BlockStatement) String() string {
+    if len(bs.Statements) == 0 {
+        return "{ }"
+    }
+
+    var out bytes.Buffer
+    out.WriteString("{ ")
+    for _, s := range bs.Statements {
+        out.WriteString(s.String())
+    }
+    out.WriteString(" }")
+
+    return out.String()
+}
+
+type IfExpression struct {
+    Token token.Token // The if token
+    Condition Expression
+    Consequence *BlockStatement
+    Alternative *BlockStatement
+}
+
+func (ie *IfExpression) expressionNode() {}
+func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+    if ie.Alternative != nil {
+        return ie.Alternative.End()
+    }
+    if ie.Consequence != nil {
+        return ie.Consequence.End()
+    }
+    return ie.Token.End
+}
+func (ie *IfExpression) String() string {
+    var out bytes.Buffer
+    out.WriteString("if ")
+    out.WriteString(ie.Condition.String())
+    out.WriteString(" ")
+    out.WriteString(ie.Consequence.String())
+
+    if ie.Alternative != nil {
+        out.WriteString(" else ")
+        out.WriteString(ie.Alternative.String())
+    }
+
+    return out.String()
+}
+
+type WhileStatement struct {
+    Token token.Token // The while token
+    Label *Identifier // nil unless the loop is labeled
+    Condition Expression
+    Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position { return labelPos(ws.Label, ws.Token) }
+func (ws *WhileStatement) End() token.Position {
+    if ws.Body != nil {
+        return ws.Body.End()
+    }
+    return ws.Token.End
+}
+func (ws *WhileStatement) String() string {
+    var out bytes.Buffer
+    out.WriteString(labelString(ws.Label))
+    out.WriteString("while ")
+    out.WriteString(ws.Condition.String())
+    out.WriteString(" ")
+    out.WriteString(ws.Body.String())
+
+    return out.String()
+}
+
+// With a single loop variable only Value is set. It takes the elements of
+// arrays and strings, and the keys of hashes.
+type ForStatement struct {
+    Token token.Token // The for token
+    Label *Identifier // nil unless the loop is labeled
+    Key *Identifier
+    Value *Identifier
+    Iterable Expression
+    Body *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position { return labelPos(fs.Label, fs.Token) }
+func (fs *ForStatement) End() token.Position {
+    if fs.Body != nil {
+        return fs.Body.End()
+    }
+    return fs.Token.End
+}
+func (fs *ForStatement) String() string {
+    var out bytes.Buffer
+    out.WriteString(labelString(fs.Label))
+    out.WriteString("for ")
+    if fs.Key != nil {
+        out.WriteString(fs.Key.String() + ", ")
+    }
+    out.WriteString(fs.Value.String())
+    out.WriteString(" in ")
+    out.WriteString(fs.Iterable.String())
+    out.WriteString(" ")
+    out.WriteString(fs.Body.String())
+
+    return out.String()
+}
+
+type BreakStatement struct {
+    Token token.Token // The break token
+    Label *Identifier // nil when breaking out of the innermost loop
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position {
+    if bs.Label != nil {
+        return bs.Label.End()
+    }
+    return bs.Token.End
+}
+func (bs *BreakStatement) String() string { return branchString(bs.Token, bs.Label) }
+
+type ContinueStatement struct {
+    Token token.Token // The continue token
+    Label *Identifier // nil when continuing the innermost loop
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position {
+    if cs.Label != nil {
+        return cs.Label.End()
+    }
+    return cs.Token.End
+}
+func (cs *ContinueStatement) String() string { return branchString(cs.Token, cs.Label) }
+
+// A labeled loop starts at its label
+func labelPos(label *Identifier, tok token.Token) token.Position {
+    if label != nil {
+        return label.Pos()
+    }
+    return tok.Pos
+}
+
+func labelString(label *Identifier) string {
+    if label == nil {
+        return ""
+    }
+    return label.String() + ": "
+}
+
+func branchString(tok token.Token, label *Identifier) string {
+    if label == nil {
+        return tok.Literal + ";"
+    }
+    return tok.Literal + " " + label.String() + ";"
+}
+
+type FunctionLiteral struct {
+    Token token.Token // The fn token
+    Parameters []*Identifier
+    Body *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode() {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+    if fl.Body != nil {
+        return fl.Body.End()
+    }
+    return fl.Token.End
+}
+func (fl *FunctionLiteral) String() string {
+    var out bytes.Buffer
+
+    params := []string{}
+    for _, p := range fl.Parameters {
+        params = append(params, p.String())
+    }
+
+    out.WriteString(fl.TokenLiteral())
+    out.WriteString("(")
+    out.WriteString(strings.Join(params, ", "))
+    out.WriteString(") ")
+    out.WriteString(fl.Body.String())
+
+    return out.String()
+}
+
+type CallExpression struct {
+    Token token.Token // The ( token
+    Function Expression // Identifier or FunctionLiteral
+    Arguments []Expression
+    RParen token.Token
+}
+
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position {
+    if ce.Function != nil {
+        return ce.Function.Pos()
+    }
+    return ce.Token.Pos
+}
+func (ce *CallExpression) End() token.Position { return ce.RParen.End }
+func (ce *CallExpression) String() string {
+    var out bytes.Buffer
+
+    args := []string{}
+    for _, a := range ce.Arguments {
+        args = append(args, a.String())
+    }
+
+    out.WriteString(ce.Function.String())
+    out.WriteString("(")
+    out.WriteString(strings.Join(args, ", "))
+    out.WriteString(")")
+
+    return out.String()
+}
+
+type StringLiteral struct {
+    Token token.Token
+    Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
+func (sl *StringLiteral) String() string { return Quote(sl.Value) }
+
+// Quote renders s as a double quoted Monkey string literal, escaping only
+// what the lexer cannot read back verbatim
+func Quote(s string) string {
+    var out bytes.Buffer
+    out.WriteByte('"')
+
+    for i := 0; i < len(s); i++ {
+        ch := s[i]
+        switch ch {
+        case '"', '\\':
+            out.WriteByte('\\')
+            out.WriteByte(ch)
+        case '\n':
+            out.WriteString("\\n")
+        case '\t':
+            out.WriteString("\\t")
+        case '\r':
+            out.WriteString("\\r")
+        default:
+            if ch < 0x20 || ch == 0x7f {
+                out.WriteString(fmt.Sprintf("\\x%02x", ch))
+            } else {
+                out.WriteByte(ch)
+            }
+        }
+    }
+
+    out.WriteByte('"')
+    return out.String()
+}
+
+type ArrayLiteral struct {
+    Token token.Token // The [ token
+    Elements []Expression
+    RBracket token.Token
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position { return al.RBracket.End }
+func (al *ArrayLiteral) String() string {
+    var out bytes.Buffer
+
+    elements := []string{}
+    for _, el := range al.Elements {
+        elements = append(elements, el.String())
+    }
+
+    out.WriteString("[")
+    out.WriteString(strings.Join(elements, ", "))
+    out.WriteString("]")
+
+    return out.String()
+}
+
+type IndexExpression struct {
+    Token token.Token // The [ token
+    Left Expression
+    Index Expression
+    RBracket token.Token
+}
+
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position {
+    if ie.Left != nil {
+        return ie.Left.Pos()
+    }
+    return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position { return ie.RBracket.End }
+func (ie *IndexExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(ie.Left.String())
+    out.WriteString("[")
+    out.WriteString(ie.Index.String())
+    out.WriteString("])")
+
+    return out.String()
+}
+
+// SliceExpression is a[low:high], where either bound may be left out
+type SliceExpression struct {
+    Token token.Token // The [ token
+    Left Expression
+    Low Expression
+    High Expression
+    RBracket token.Token
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position {
+    if se.Left != nil {
+        return se.Left.Pos()
+    }
+    return se.Token.Pos
+}
+func (se *SliceExpression) End() token.Position { return se.RBracket.End }
+func (se *SliceExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(se.Left.String())
+    out.WriteString("[")
+    if se.Low != nil {
+        out.WriteString(se.Low.String())
+    }
+    out.WriteString(":")
+    if se.High != nil {
+        out.WriteString(se.High.String())
+    }
+    out.WriteString("])")
+
+    return out.String()
+}
+
+type HashPair struct {
+    Key Expression
+    Value Expression
+}
+
+// The pairs keep their source order
+type HashLiteral struct {
+    Token token.Token // The { token
+    Pairs []HashPair
+    RBrace token.Token
+}
+
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position { return hl.RBrace.End }
+func (hl *HashLiteral) String() string {
+    var out bytes.Buffer
+
+    pairs := []string{}
+    for _, pair := range hl.Pairs {
+        pairs = append(pairs, pair.Key.String() + ": " + pair.Value.String())
+    }
+
+    out.WriteString("{")
+    out.WriteString(strings.Join(pairs, ", "))
+    out.WriteString("}")
+
+    return out.String()
+}
+
+// AssignExpression is `target = value` or a compound form like `target += value`
+type AssignExpression struct {
+    Token token.Token // The assignment operator
+    Target Expression // Identifier or IndexExpression
+    Operator string
+    Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position {
+    if ae.Target != nil {
+        return ae.Target.Pos()
+    }
+    return ae.Token.Pos
+}
+func (ae *AssignExpression) End() token.Position {
+    if ae.Value != nil {
+        return ae.Value.End()
+    }
+    return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+    var out bytes.Buffer
+    out.WriteString("(")
+    out.WriteString(ae.Target.String())
+    out.WriteString(" " + ae.Operator + " ")
+    out.WriteString(ae.Value.String())
+    out.WriteString(")")
+
+    return out.String()
+}
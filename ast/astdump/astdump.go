@@ -0,0 +1,132 @@
+// Package astdump renders Monkey syntax trees for people: as an indented
+// S-expression or as a Graphviz DOT graph.
+package astdump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkeylang/ast"
+	"strings"
+)
+
+// FprintSExpr writes node as an S-expression with one node per line.
+// Fields are labeled with their names, elements of lists are not.
+//
+//	(ExpressionStatement
+//	  Expression: (InfixExpression *
+//	    Left: (PrefixExpression -
+//	      Right: (Identifier a))
+//	    Right: (Identifier b)))
+func FprintSExpr(w io.Writer, node ast.Node) error {
+    out := bufio.NewWriter(w)
+    depth := 0
+
+    ast.Apply(node, func(c *ast.Cursor) bool {
+        if depth > 0 {
+            out.WriteString("\n" + strings.Repeat("  ", depth))
+            if c.Index() < 0 || isHash(c.Parent()) {
+                out.WriteString(c.Name() + ": ")
+            }
+        }
+
+        kind, detail := describe(c.Node())
+        out.WriteString("(" + kind)
+        if detail != "" {
+            out.WriteString(" " + detail)
+        }
+
+        depth += 1
+        return true
+    }, func(c *ast.Cursor) bool {
+        out.WriteString(")")
+        depth -= 1
+        return true
+    })
+
+    if node != nil {
+        out.WriteString("\n")
+    }
+    return out.Flush()
+}
+
+// FprintDOT writes node as a Graphviz digraph. Every node is labeled with
+// its kind and its literal or operator, every edge with the field it comes
+// from, e.g. Left or Arguments[1].
+func FprintDOT(w io.Writer, node ast.Node) error {
+    out := bufio.NewWriter(w)
+    out.WriteString("digraph AST {\n")
+    out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+    ids := 0
+    parents := []int{}
+
+    ast.Apply(node, func(c *ast.Cursor) bool {
+        id := ids
+        ids += 1
+
+        kind, detail := describe(c.Node())
+        label := kind
+        if detail != "" {
+            label += "\n" + detail
+        }
+        fmt.Fprintf(out, "  n%d [label=%s];\n", id, dotQuote(label))
+
+        if len(parents) > 0 {
+            edge := c.Name()
+            if c.Index() >= 0 {
+                edge = fmt.Sprintf("%s[%d]", edge, c.Index())
+            }
+            fmt.Fprintf(out, "  n%d -> n%d [label=%s];\n", parents[len(parents)-1], id, dotQuote(edge))
+        }
+
+        parents = append(parents, id)
+        return true
+    }, func(c *ast.Cursor) bool {
+        parents = parents[:len(parents)-1]
+        return true
+    })
+
+    out.WriteString("}\n")
+    return out.Flush()
+}
+
+// The kind of a node is its type name, the detail its literal or operator
+func describe(node ast.Node) (kind, detail string) {
+    kind = fmt.Sprintf("%T", node)[len("*ast."):]
+
+    switch n := node.(type) {
+    case *ast.Identifier:
+        detail = n.Value
+    case *ast.IntegerLiteral:
+        detail = n.Token.Literal
+    case *ast.FloatLiteral:
+        detail = n.Token.Literal
+    case *ast.StringLiteral:
+        detail = ast.Quote(n.Value)
+    case *ast.Boolean:
+        detail = n.Token.Literal
+    case *ast.PrefixExpression:
+        detail = n.Operator
+    case *ast.InfixExpression:
+        detail = n.Operator
+    case *ast.AssignExpression:
+        detail = n.Operator
+    }
+
+    return kind, detail
+}
+
+// Keys and values of a hash literal sit in one list, so they keep their
+// labels to tell them apart
+func isHash(node ast.Node) bool {
+    _, ok := node.(*ast.HashLiteral)
+    return ok
+}
+
+// Backslashes, quotes and newlines have to be escaped in DOT strings
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+    return `"` + dotEscaper.Replace(s) + `"`
+}
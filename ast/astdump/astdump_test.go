@@ -0,0 +1,110 @@
+package astdump
+
+import (
+	"bytes"
+	"monkeylang/ast"
+	"monkeylang/lexer"
+	"monkeylang/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+
+    if errors := p.Errors(); len(errors) != 0 {
+        t.Fatalf("%q has parse errors %v", input, errors)
+    }
+    return program
+}
+
+func TestSExpr(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"-a * b", `
+(Program
+  (ExpressionStatement
+    Expression: (InfixExpression *
+      Left: (PrefixExpression -
+        Right: (Identifier a))
+      Right: (Identifier b))))
+`},
+        {`let s = f("x\n", 2.5)[:1];`, `
+(Program
+  (LetStatement
+    Name: (Identifier s)
+    Value: (SliceExpression
+      Left: (CallExpression
+        Function: (Identifier f)
+        (StringLiteral "x\n")
+        (FloatLiteral 2.5))
+      High: (IntegerLiteral 1))))
+`},
+        {"{true: [1]}", `
+(Program
+  (ExpressionStatement
+    Expression: (HashLiteral
+      Key: (Boolean true)
+      Value: (ArrayLiteral
+        (IntegerLiteral 1)))))
+`},
+    }
+
+    for _, tt := range tests {
+        var out bytes.Buffer
+        if err := FprintSExpr(&out, parse(t, tt.input)); err != nil {
+            t.Fatalf("FprintSExpr failed: %v", err)
+        }
+
+        expected := strings.TrimPrefix(tt.expected, "\n")
+        if out.String() != expected {
+            t.Errorf("%q\nexpected\n%s\ngot\n%s", tt.input, expected, out.String())
+        }
+    }
+}
+
+func TestSExprOfExpression(t *testing.T) {
+    program := parse(t, "x += 1")
+    exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+    var out bytes.Buffer
+    FprintSExpr(&out, exp)
+
+    expected := "(AssignExpression +=\n  Target: (Identifier x)\n  Value: (IntegerLiteral 1))\n"
+    if out.String() != expected {
+        t.Errorf("expected %q got %q", expected, out.String())
+    }
+}
+
+func TestDOT(t *testing.T) {
+    var out bytes.Buffer
+    if err := FprintDOT(&out, parse(t, `f(1 + 2, "a\"b")`)); err != nil {
+        t.Fatalf("FprintDOT failed: %v", err)
+    }
+
+    expected := `digraph AST {
+  node [shape=box, fontname="monospace"];
+  n0 [label="Program"];
+  n1 [label="ExpressionStatement"];
+  n0 -> n1 [label="Statements[0]"];
+  n2 [label="CallExpression"];
+  n1 -> n2 [label="Expression"];
+  n3 [label="Identifier\nf"];
+  n2 -> n3 [label="Function"];
+  n4 [label="InfixExpression\n+"];
+  n2 -> n4 [label="Arguments[0]"];
+  n5 [label="IntegerLiteral\n1"];
+  n4 -> n5 [label="Left"];
+  n6 [label="IntegerLiteral\n2"];
+  n4 -> n6 [label="Right"];
+  n7 [label="StringLiteral\n\"a\\\"b\""];
+  n2 -> n7 [label="Arguments[1]"];
+}
+`
+    if out.String() != expected {
+        t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+    }
+}
@@ -0,0 +1,136 @@
+package astjson
+
+import (
+	"fmt"
+	"monkeylang/ast"
+	"monkeylang/lexer"
+	"monkeylang/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+    p := parser.New(lexer.NewFile("test.mk", input))
+    program := p.ParseProgram()
+
+    if errors := p.Errors(); len(errors) != 0 {
+        t.Fatalf("%q has parse errors %v", input, errors)
+    }
+    return program
+}
+
+func TestRoundTrip(t *testing.T) {
+    input := `
+    // Every kind of node
+    let add = fn(a, b) { return a + b; }; # trailing
+    let xs = [1, 2.5, "three", true, -x, !false];
+    let h = {"k": xs[0], 1: xs[1:2]};
+    if (add(1, 2) > 2) { h["k"] += 1 } else { return; }
+    outer: for (k, v in h) {
+        while v { /* block */ break outer; continue }
+    }
+    `
+    program := parse(t, input)
+
+    first, err := Marshal(program)
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+
+    decoded, err := Unmarshal(first)
+    if err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+
+    second, err := Marshal(decoded)
+    if err != nil {
+        t.Fatalf("Marshal of the decoded tree failed: %v", err)
+    }
+
+    if string(first) != string(second) {
+        t.Errorf("re-encoding changed the document\nfirst  %s\nsecond %s", first, second)
+    }
+
+    if decoded.String() != program.String() {
+        t.Errorf("expected %q got %q", program.String(), decoded.String())
+    }
+    if decoded.Pos() != program.Pos() || decoded.End() != program.End() {
+        t.Errorf("positions changed %s-%s became %s-%s", program.Pos(), program.End(), decoded.Pos(), decoded.End())
+    }
+    if len(decoded.(*ast.Program).Comments) != 3 {
+        t.Errorf("expected 3 comments got %d", len(decoded.(*ast.Program).Comments))
+    }
+}
+
+func TestEncoding(t *testing.T) {
+    program := parse(t, "-x")
+    prefix := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+    data, err := Marshal(prefix)
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+
+    pos := func(offset, column int) string {
+        return fmt.Sprintf(`{"filename":"test.mk","offset":%d,"line":1,"column":%d}`, offset, column)
+    }
+    expected := `{"kind":"PrefixExpression",` +
+        `"token":{"type":"-","literal":"-","pos":` + pos(0, 1) + `,"end":` + pos(1, 2) + `},` +
+        `"pos":` + pos(0, 1) + `,"end":` + pos(2, 3) + `,` +
+        `"operator":"-",` +
+        `"right":{"kind":"Identifier",` +
+        `"token":{"type":"IDENT","literal":"x","pos":` + pos(1, 2) + `,"end":` + pos(2, 3) + `},` +
+        `"pos":` + pos(1, 2) + `,"end":` + pos(2, 3) + `,"value":"x"}}`
+
+    if string(data) != expected {
+        t.Errorf("wrong encoding\nexpected %s\ngot      %s", expected, data)
+    }
+}
+
+func TestMissingChildren(t *testing.T) {
+    data, err := Marshal(&ast.ReturnStatement{})
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+    if !strings.Contains(string(data), `"returnValue":null`) {
+        t.Errorf("expected a null return value got %s", data)
+    }
+
+    node, err := Unmarshal(data)
+    if err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    if ret := node.(*ast.ReturnStatement); ret.ReturnValue != nil {
+        t.Errorf("expected no return value got %v", ret.ReturnValue)
+    }
+}
+
+func TestDecodeErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {`[]`, "astjson: root: json: cannot unmarshal array into Go value of type astjson.fields"},
+        {`{}`, `astjson: root: missing field "kind"`},
+        {`{"kind": "Loop"}`, `astjson: root: unknown node kind "Loop"`},
+        {`{"kind": "Program", "statements": [{"kind": "Bogus"}], "comments": []}`,
+            `astjson: statements[0]: unknown node kind "Bogus"`},
+        {`{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "Program", "statements": [], "comments": []}}`,
+            "astjson: expression: Program is not an expression"},
+        {`{"kind": "LetStatement", "token": {}, "name": {"kind": "Boolean", "token": {}, "value": true}, "value": null}`,
+            "astjson: name: Boolean is not an Identifier"},
+        {`{"kind": "IntegerLiteral", "token": {}, "value": "1"}`,
+            "astjson: value: json: cannot unmarshal string into Go value of type int64"},
+    }
+
+    for _, tt := range tests {
+        _, err := Unmarshal([]byte(tt.input))
+        if err == nil {
+            t.Errorf("%s expected an error", tt.input)
+            continue
+        }
+        if err.Error() != tt.expected {
+            t.Errorf("%s expected %q got %q", tt.input, tt.expected, err.Error())
+        }
+    }
+}
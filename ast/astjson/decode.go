@@ -0,0 +1,393 @@
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkeylang/ast"
+	"monkeylang/token"
+)
+
+// Unmarshal decodes a tree written by Marshal. The "pos" and "end" of nodes
+// are ignored, they follow from the tokens.
+func Unmarshal(data []byte) (ast.Node, error) {
+    d := &decoder{}
+
+    node := d.node(json.RawMessage(data), "")
+    if d.err != nil {
+        return nil, d.err
+    }
+    return node, nil
+}
+
+// The decoder remembers the first error and turns every later step into a
+// no-op, so that decoding a node reads like a list of its fields
+type decoder struct {
+    err error
+}
+
+func (d *decoder) fail(path, format string, args ...interface{}) {
+    if d.err == nil {
+        if path == "" {
+            path = "root"
+        }
+        d.err = fmt.Errorf("astjson: %s: %s", path, fmt.Sprintf(format, args...))
+    }
+}
+
+type fields map[string]json.RawMessage
+
+func (d *decoder) object(data json.RawMessage, path string) fields {
+    var f fields
+    if err := json.Unmarshal(data, &f); err != nil {
+        d.fail(path, "%v", err)
+    }
+    return f
+}
+
+// Decodes a node of any kind, null becomes nil
+func (d *decoder) node(data json.RawMessage, path string) ast.Node {
+    if d.err != nil || isNull(data) {
+        return nil
+    }
+
+    f := d.object(data, path)
+    var kind string
+    d.value(f, "kind", path, &kind)
+    if d.err != nil {
+        return nil
+    }
+
+    switch kind {
+    case "Program":
+        return &ast.Program{
+            Statements: d.statements(f, "statements", path),
+            Comments: d.comments(f, "comments", path),
+        }
+
+    case "LetStatement":
+        return &ast.LetStatement{
+            Token: d.token(f, "token", path),
+            Name: d.identifier(f, "name", path),
+            Value: d.expression(f, "value", path),
+        }
+
+    case "ReturnStatement":
+        return &ast.ReturnStatement{
+            Token: d.token(f, "token", path),
+            ReturnValue: d.expression(f, "returnValue", path),
+        }
+
+    case "ExpressionStatement":
+        return &ast.ExpressionStatement{
+            Token: d.token(f, "token", path),
+            Expression: d.expression(f, "expression", path),
+        }
+
+    case "BlockStatement":
+        return &ast.BlockStatement{
+            Token: d.token(f, "token", path),
+            Statements: d.statements(f, "statements", path),
+            RBrace: d.token(f, "rbrace", path),
+        }
+
+    case "WhileStatement":
+        return &ast.WhileStatement{
+            Token: d.token(f, "token", path),
+            Label: d.identifier(f, "label", path),
+            Condition: d.expression(f, "condition", path),
+            Body: d.block(f, "body", path),
+        }
+
+    case "ForStatement":
+        return &ast.ForStatement{
+            Token: d.token(f, "token", path),
+            Label: d.identifier(f, "label", path),
+            Key: d.identifier(f, "key", path),
+            Value: d.identifier(f, "value", path),
+            Iterable: d.expression(f, "iterable", path),
+            Body: d.block(f, "body", path),
+        }
+
+    case "BreakStatement":
+        return &ast.BreakStatement{
+            Token: d.token(f, "token", path),
+            Label: d.identifier(f, "label", path),
+        }
+
+    case "ContinueStatement":
+        return &ast.ContinueStatement{
+            Token: d.token(f, "token", path),
+            Label: d.identifier(f, "label", path),
+        }
+
+    case "Identifier":
+        n := &ast.Identifier{Token: d.token(f, "token", path)}
+        d.value(f, "value", path, &n.Value)
+        return n
+
+    case "IntegerLiteral":
+        n := &ast.IntegerLiteral{Token: d.token(f, "token", path)}
+        d.value(f, "value", path, &n.Value)
+        return n
+
+    case "FloatLiteral":
+        n := &ast.FloatLiteral{Token: d.token(f, "token", path)}
+        d.value(f, "value", path, &n.Value)
+        return n
+
+    case "StringLiteral":
+        n := &ast.StringLiteral{Token: d.token(f, "token", path)}
+        d.value(f, "value", path, &n.Value)
+        return n
+
+    case "Boolean":
+        n := &ast.Boolean{Token: d.token(f, "token", path)}
+        d.value(f, "value", path, &n.Value)
+        return n
+
+    case "PrefixExpression":
+        n := &ast.PrefixExpression{
+            Token: d.token(f, "token", path),
+            Right: d.expression(f, "right", path),
+        }
+        d.value(f, "operator", path, &n.Operator)
+        return n
+
+    case "InfixExpression":
+        n := &ast.InfixExpression{
+            Token: d.token(f, "token", path),
+            Left: d.expression(f, "left", path),
+            Right: d.expression(f, "right", path),
+        }
+        d.value(f, "operator", path, &n.Operator)
+        return n
+
+    case "IfExpression":
+        return &ast.IfExpression{
+            Token: d.token(f, "token", path),
+            Condition: d.expression(f, "condition", path),
+            Consequence: d.block(f, "consequence", path),
+            Alternative: d.block(f, "alternative", path),
+        }
+
+    case "FunctionLiteral":
+        n := &ast.FunctionLiteral{Token: d.token(f, "token", path)}
+        for i, raw := range d.list(f, "parameters", path) {
+            n.Parameters = append(n.Parameters, d.identifierAt(raw, fmt.Sprintf("%s.parameters[%d]", path, i)))
+        }
+        n.Body = d.block(f, "body", path)
+        return n
+
+    case "CallExpression":
+        return &ast.CallExpression{
+            Token: d.token(f, "token", path),
+            Function: d.expression(f, "function", path),
+            Arguments: d.expressions(f, "arguments", path),
+            RParen: d.token(f, "rparen", path),
+        }
+
+    case "ArrayLiteral":
+        return &ast.ArrayLiteral{
+            Token: d.token(f, "token", path),
+            Elements: d.expressions(f, "elements", path),
+            RBracket: d.token(f, "rbracket", path),
+        }
+
+    case "IndexExpression":
+        return &ast.IndexExpression{
+            Token: d.token(f, "token", path),
+            Left: d.expression(f, "left", path),
+            Index: d.expression(f, "index", path),
+            RBracket: d.token(f, "rbracket", path),
+        }
+
+    case "SliceExpression":
+        return &ast.SliceExpression{
+            Token: d.token(f, "token", path),
+            Left: d.expression(f, "left", path),
+            Low: d.expression(f, "low", path),
+            High: d.expression(f, "high", path),
+            RBracket: d.token(f, "rbracket", path),
+        }
+
+    case "HashLiteral":
+        n := &ast.HashLiteral{Token: d.token(f, "token", path)}
+        for i, raw := range d.list(f, "pairs", path) {
+            pairPath := fmt.Sprintf("%s.pairs[%d]", path, i)
+            pair := d.object(raw, pairPath)
+            n.Pairs = append(n.Pairs, ast.HashPair{
+                Key: d.expression(pair, "key", pairPath),
+                Value: d.expression(pair, "value", pairPath),
+            })
+        }
+        n.RBrace = d.token(f, "rbrace", path)
+        return n
+
+    case "AssignExpression":
+        n := &ast.AssignExpression{
+            Token: d.token(f, "token", path),
+            Target: d.expression(f, "target", path),
+            Value: d.expression(f, "value", path),
+        }
+        d.value(f, "operator", path, &n.Operator)
+        return n
+    }
+
+    d.fail(path, "unknown node kind %q", kind)
+    return nil
+}
+
+func isNull(data json.RawMessage) bool {
+    return len(data) == 0 || string(data) == "null"
+}
+
+func fieldPath(path, name string) string {
+    if path == "" {
+        return name
+    }
+    return path + "." + name
+}
+
+// Decodes a plain JSON value such as a string or a number
+func (d *decoder) value(f fields, name, path string, v interface{}) {
+    if d.err != nil {
+        return
+    }
+
+    raw, ok := f[name]
+    if !ok {
+        d.fail(path, "missing field %q", name)
+        return
+    }
+    if err := json.Unmarshal(raw, v); err != nil {
+        d.fail(fieldPath(path, name), "%v", err)
+    }
+}
+
+func (d *decoder) list(f fields, name, path string) []json.RawMessage {
+    var list []json.RawMessage
+    d.value(f, name, path, &list)
+    return list
+}
+
+func (d *decoder) expression(f fields, name, path string) ast.Expression {
+    path = fieldPath(path, name)
+    node := d.node(f[name], path)
+    if node == nil {
+        return nil
+    }
+
+    exp, ok := node.(ast.Expression)
+    if !ok {
+        d.fail(path, "%s is not an expression", kindOf(node))
+    }
+    return exp
+}
+
+func (d *decoder) expressions(f fields, name, path string) []ast.Expression {
+    list := []ast.Expression{}
+    for i, raw := range d.list(f, name, path) {
+        elPath := fmt.Sprintf("%s[%d]", fieldPath(path, name), i)
+        node := d.node(raw, elPath)
+
+        exp, ok := node.(ast.Expression)
+        if !ok && node != nil {
+            d.fail(elPath, "%s is not an expression", kindOf(node))
+        }
+        list = append(list, exp)
+    }
+    return list
+}
+
+func (d *decoder) statements(f fields, name, path string) []ast.Statement {
+    list := []ast.Statement{}
+    for i, raw := range d.list(f, name, path) {
+        elPath := fmt.Sprintf("%s[%d]", fieldPath(path, name), i)
+        node := d.node(raw, elPath)
+
+        stmt, ok := node.(ast.Statement)
+        if !ok && node != nil {
+            d.fail(elPath, "%s is not a statement", kindOf(node))
+        }
+        list = append(list, stmt)
+    }
+    return list
+}
+
+func (d *decoder) identifier(f fields, name, path string) *ast.Identifier {
+    return d.identifierAt(f[name], fieldPath(path, name))
+}
+
+func (d *decoder) identifierAt(data json.RawMessage, path string) *ast.Identifier {
+    node := d.node(data, path)
+    if node == nil {
+        return nil
+    }
+
+    ident, ok := node.(*ast.Identifier)
+    if !ok {
+        d.fail(path, "%s is not an Identifier", kindOf(node))
+    }
+    return ident
+}
+
+func (d *decoder) block(f fields, name, path string) *ast.BlockStatement {
+    path = fieldPath(path, name)
+    node := d.node(f[name], path)
+    if node == nil {
+        return nil
+    }
+
+    block, ok := node.(*ast.BlockStatement)
+    if !ok {
+        d.fail(path, "%s is not a BlockStatement", kindOf(node))
+    }
+    return block
+}
+
+type jsonComment struct {
+    Text string `json:"text"`
+    Pos token.Position `json:"pos"`
+    End token.Position `json:"end"`
+}
+
+type jsonToken struct {
+    Type token.TokenType `json:"type"`
+    Literal string `json:"literal"`
+    Pos token.Position `json:"pos"`
+    End token.Position `json:"end"`
+    Leading []jsonComment `json:"leading"`
+    Trailing []jsonComment `json:"trailing"`
+}
+
+func (d *decoder) token(f fields, name, path string) token.Token {
+    var t jsonToken
+    d.value(f, name, path, &t)
+
+    return token.Token{
+        Type: t.Type,
+        Literal: t.Literal,
+        Pos: t.Pos,
+        End: t.End,
+        Leading: toComments(t.Leading),
+        Trailing: toComments(t.Trailing),
+    }
+}
+
+func (d *decoder) comments(f fields, name, path string) []token.Comment {
+    var list []jsonComment
+    d.value(f, name, path, &list)
+    return toComments(list)
+}
+
+func toComments(list []jsonComment) []token.Comment {
+    if len(list) == 0 {
+        return nil
+    }
+
+    comments := []token.Comment{}
+    for _, c := range list {
+        comments = append(comments, token.Comment{Text: c.Text, Pos: c.Pos, End: c.End})
+    }
+    return comments
+}
@@ -0,0 +1,286 @@
+// Package astjson converts Monkey syntax trees to and from JSON.
+//
+// Every node becomes an object whose "kind" is the name of its type in the
+// ast package, followed by its token, its position range and its fields.
+// Tokens keep their comments, so decoding a tree and encoding it again gives
+// the same document.
+//
+//	{"kind": "PrefixExpression", "token": {...}, "pos": {...}, "end": {...},
+//	 "operator": "-", "right": {"kind": "Identifier", ...}}
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monkeylang/ast"
+	"monkeylang/token"
+	"reflect"
+)
+
+// Marshal encodes node and everything below it
+func Marshal(node ast.Node) ([]byte, error) {
+    return json.Marshal(encode(node))
+}
+
+// Like Marshal but with one field per line, see json.MarshalIndent
+func MarshalIndent(node ast.Node, prefix, indent string) ([]byte, error) {
+    return json.MarshalIndent(encode(node), prefix, indent)
+}
+
+// An object keeps its fields in order, unlike a map
+type object []field
+
+type field struct {
+    name string
+    value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+    var out bytes.Buffer
+    out.WriteString("{")
+
+    for i, f := range o {
+        if i > 0 {
+            out.WriteString(",")
+        }
+
+        name, _ := json.Marshal(f.name)
+        value, err := json.Marshal(f.value)
+        if err != nil {
+            return nil, err
+        }
+
+        out.Write(name)
+        out.WriteString(":")
+        out.Write(value)
+    }
+
+    out.WriteString("}")
+    return out.Bytes(), nil
+}
+
+func encode(node ast.Node) interface{} {
+    // Missing children, including nil pointers of a concrete node type
+    if node == nil || reflect.ValueOf(node).IsNil() {
+        return nil
+    }
+
+    switch n := node.(type) {
+    case *ast.Program:
+        return nodeObject(n, nil,
+            field{"statements", encodeStatements(n.Statements)},
+            field{"comments", encodeComments(n.Comments)},
+        )
+
+    case *ast.LetStatement:
+        return nodeObject(n, &n.Token,
+            field{"name", encode(n.Name)},
+            field{"value", encode(n.Value)},
+        )
+
+    case *ast.ReturnStatement:
+        return nodeObject(n, &n.Token, field{"returnValue", encode(n.ReturnValue)})
+
+    case *ast.ExpressionStatement:
+        return nodeObject(n, &n.Token, field{"expression", encode(n.Expression)})
+
+    case *ast.BlockStatement:
+        return nodeObject(n, &n.Token,
+            field{"statements", encodeStatements(n.Statements)},
+            field{"rbrace", encodeToken(n.RBrace)},
+        )
+
+    case *ast.WhileStatement:
+        return nodeObject(n, &n.Token,
+            field{"label", encode(n.Label)},
+            field{"condition", encode(n.Condition)},
+            field{"body", encode(n.Body)},
+        )
+
+    case *ast.ForStatement:
+        return nodeObject(n, &n.Token,
+            field{"label", encode(n.Label)},
+            field{"key", encode(n.Key)},
+            field{"value", encode(n.Value)},
+            field{"iterable", encode(n.Iterable)},
+            field{"body", encode(n.Body)},
+        )
+
+    case *ast.BreakStatement:
+        return nodeObject(n, &n.Token, field{"label", encode(n.Label)})
+
+    case *ast.ContinueStatement:
+        return nodeObject(n, &n.Token, field{"label", encode(n.Label)})
+
+    case *ast.Identifier:
+        return nodeObject(n, &n.Token, field{"value", n.Value})
+
+    case *ast.IntegerLiteral:
+        return nodeObject(n, &n.Token, field{"value", n.Value})
+
+    case *ast.FloatLiteral:
+        return nodeObject(n, &n.Token, field{"value", n.Value})
+
+    case *ast.StringLiteral:
+        return nodeObject(n, &n.Token, field{"value", n.Value})
+
+    case *ast.Boolean:
+        return nodeObject(n, &n.Token, field{"value", n.Value})
+
+    case *ast.PrefixExpression:
+        return nodeObject(n, &n.Token,
+            field{"operator", n.Operator},
+            field{"right", encode(n.Right)},
+        )
+
+    case *ast.InfixExpression:
+        return nodeObject(n, &n.Token,
+            field{"left", encode(n.Left)},
+            field{"operator", n.Operator},
+            field{"right", encode(n.Right)},
+        )
+
+    case *ast.IfExpression:
+        return nodeObject(n, &n.Token,
+            field{"condition", encode(n.Condition)},
+            field{"consequence", encode(n.Consequence)},
+            field{"alternative", encode(n.Alternative)},
+        )
+
+    case *ast.FunctionLiteral:
+        params := []interface{}{}
+        for _, p := range n.Parameters {
+            params = append(params, encode(p))
+        }
+        return nodeObject(n, &n.Token,
+            field{"parameters", params},
+            field{"body", encode(n.Body)},
+        )
+
+    case *ast.CallExpression:
+        return nodeObject(n, &n.Token,
+            field{"function", encode(n.Function)},
+            field{"arguments", encodeExpressions(n.Arguments)},
+            field{"rparen", encodeToken(n.RParen)},
+        )
+
+    case *ast.ArrayLiteral:
+        return nodeObject(n, &n.Token,
+            field{"elements", encodeExpressions(n.Elements)},
+            field{"rbracket", encodeToken(n.RBracket)},
+        )
+
+    case *ast.IndexExpression:
+        return nodeObject(n, &n.Token,
+            field{"left", encode(n.Left)},
+            field{"index", encode(n.Index)},
+            field{"rbracket", encodeToken(n.RBracket)},
+        )
+
+    case *ast.SliceExpression:
+        return nodeObject(n, &n.Token,
+            field{"left", encode(n.Left)},
+            field{"low", encode(n.Low)},
+            field{"high", encode(n.High)},
+            field{"rbracket", encodeToken(n.RBracket)},
+        )
+
+    case *ast.HashLiteral:
+        pairs := []interface{}{}
+        for _, pair := range n.Pairs {
+            pairs = append(pairs, object{
+                {"key", encode(pair.Key)},
+                {"value", encode(pair.Value)},
+            })
+        }
+        return nodeObject(n, &n.Token,
+            field{"pairs", pairs},
+            field{"rbrace", encodeToken(n.RBrace)},
+        )
+
+    case *ast.AssignExpression:
+        return nodeObject(n, &n.Token,
+            field{"target", encode(n.Target)},
+            field{"operator", n.Operator},
+            field{"value", encode(n.Value)},
+        )
+    }
+
+    panic(fmt.Sprintf("astjson: unexpected node type %T", node))
+}
+
+// The fields every node starts with. The position range is derived from
+// the tokens, it is there for the benefit of other tools.
+func nodeObject(n ast.Node, tok *token.Token, fields ...field) object {
+    obj := object{{"kind", kindOf(n)}}
+    if tok != nil {
+        obj = append(obj, field{"token", encodeToken(*tok)})
+    }
+    obj = append(obj, field{"pos", encodePosition(n.Pos())}, field{"end", encodePosition(n.End())})
+
+    return append(obj, fields...)
+}
+
+func kindOf(n ast.Node) string {
+    return fmt.Sprintf("%T", n)[len("*ast."):]
+}
+
+func encodeStatements(list []ast.Statement) []interface{} {
+    out := []interface{}{}
+    for _, s := range list {
+        out = append(out, encode(s))
+    }
+    return out
+}
+
+func encodeExpressions(list []ast.Expression) []interface{} {
+    out := []interface{}{}
+    for _, e := range list {
+        out = append(out, encode(e))
+    }
+    return out
+}
+
+func encodeToken(t token.Token) object {
+    obj := object{
+        {"type", string(t.Type)},
+        {"literal", t.Literal},
+        {"pos", encodePosition(t.Pos)},
+        {"end", encodePosition(t.End)},
+    }
+
+    if len(t.Leading) > 0 {
+        obj = append(obj, field{"leading", encodeComments(t.Leading)})
+    }
+    if len(t.Trailing) > 0 {
+        obj = append(obj, field{"trailing", encodeComments(t.Trailing)})
+    }
+
+    return obj
+}
+
+func encodeComments(comments []token.Comment) []interface{} {
+    out := []interface{}{}
+    for _, c := range comments {
+        out = append(out, object{
+            {"text", c.Text},
+            {"pos", encodePosition(c.Pos)},
+            {"end", encodePosition(c.End)},
+        })
+    }
+    return out
+}
+
+func encodePosition(p token.Position) object {
+    obj := object{}
+    if p.Filename != "" {
+        obj = append(obj, field{"filename", p.Filename})
+    }
+
+    return append(obj,
+        field{"offset", p.Offset},
+        field{"line", p.Line},
+        field{"column", p.Column},
+    )
+}
@@ -0,0 +1,461 @@
+package ast
+
+import (
+	"fmt"
+	"monkeylang/token"
+)
+
+// An ApplyFunc is called by Apply for every node. Returning false from the
+// pre function skips the children of the node, returning false from the
+// post function stops the traversal.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses an AST like Walk, calling pre before the children of a
+// node are visited and post after them. Either may be nil. Through the
+// Cursor they can replace, delete and insert nodes. If pre replaces a node
+// the children of the new node are traversed, nodes inserted or deleted
+// around the current one are never visited. Apply returns the possibly
+// replaced root.
+//
+// A replacement or insertion built without positions, e.g. a literal
+// made up by constant folding, takes them over from the node it stands in
+// for, so that errors about it still point into the source.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+    defer func() {
+        if r := recover(); r != nil && r != abort {
+            panic(r)
+        }
+        result = root
+    }()
+
+    a := &application{pre: pre, post: post}
+    if root != nil {
+        a.apply(&fieldSlot[Node]{field: &root}, root)
+    }
+
+    return root
+}
+
+// Modify rewrites an AST bottom-up: the children of a node are modified
+// before fn is called for the node itself, and whatever fn returns takes
+// its place. Returning nil deletes the node from a list, such as the
+// statements of a block or the arguments of a call, and clears any other
+// field. Modify returns the possibly replaced root.
+func Modify(node Node, fn func(Node) Node) Node {
+    return Apply(node, nil, func(c *Cursor) bool {
+        if n := fn(c.Node()); n != c.Node() {
+            c.Replace(n)
+        }
+        return true
+    })
+}
+
+var abort = new(int)
+
+// A Cursor describes the node being visited by Apply and where it sits in
+// its parent
+type Cursor struct {
+    node Node
+    slot slot
+}
+
+func (c *Cursor) Node() Node { return c.node }
+
+// The parent of the current node, nil for the root
+func (c *Cursor) Parent() Node { return c.slot.parent() }
+
+// The name of the parent's field holding the current node, e.g. "Left" or
+// "Statements". Keys and values of hash literals are "Key" and "Value".
+func (c *Cursor) Name() string { return c.slot.name() }
+
+// The position of the current node in its list, or -1 if it is not in one.
+// For keys and values of hash literals it is the index of the pair.
+func (c *Cursor) Index() int { return c.slot.index() }
+
+// Replaces the current node. Replacing it with nil is the same as Delete
+// inside a list and clears the field otherwise.
+func (c *Cursor) Replace(n Node) {
+    if n == nil && c.Index() >= 0 {
+        c.Delete()
+        return
+    }
+
+    if n != nil && c.node != nil {
+        inheritPositions(n, c.node.Pos(), c.node.End())
+    }
+    c.slot.set(n)
+    c.node = n
+}
+
+// Deletes the current node from its list. A key or value of a hash literal
+// deletes the whole pair. Panics if the node is not in a list.
+func (c *Cursor) Delete() {
+    c.slot.delete()
+    c.node = nil
+}
+
+// Inserts n before the current node in its list. Panics if the node is not
+// in a list.
+func (c *Cursor) InsertBefore(n Node) {
+    if c.node != nil {
+        inheritPositions(n, c.node.Pos(), c.node.Pos())
+    }
+    c.slot.insertBefore(n)
+}
+
+// Inserts n after the current node in its list. Panics if the node is not
+// in a list.
+func (c *Cursor) InsertAfter(n Node) {
+    if c.node != nil {
+        inheritPositions(n, c.node.End(), c.node.End())
+    }
+    c.slot.insertAfter(n)
+}
+
+type application struct {
+    pre, post ApplyFunc
+    iter iterator // Position in the list being traversed
+}
+
+type iterator struct {
+    index int
+    step int // How far to move on once the current element is done
+}
+
+func (a *application) apply(s slot, n Node) {
+    c := &Cursor{node: n, slot: s}
+
+    // A node that pre deleted or cleared is done with
+    if a.pre != nil && !a.pre(c) || c.node == nil {
+        return
+    }
+
+    switch n := c.node.(type) {
+    case *Program:
+        applyList(a, n, "Statements", &n.Statements)
+
+    case *LetStatement:
+        applyField(a, n, "Name", &n.Name)
+        applyField(a, n, "Value", &n.Value)
+
+    case *ReturnStatement:
+        applyField(a, n, "ReturnValue", &n.ReturnValue)
+
+    case *ExpressionStatement:
+        applyField(a, n, "Expression", &n.Expression)
+
+    case *BlockStatement:
+        applyList(a, n, "Statements", &n.Statements)
+
+    case *WhileStatement:
+        applyField(a, n, "Label", &n.Label)
+        applyField(a, n, "Condition", &n.Condition)
+        applyField(a, n, "Body", &n.Body)
+
+    case *ForStatement:
+        applyField(a, n, "Label", &n.Label)
+        applyField(a, n, "Key", &n.Key)
+        applyField(a, n, "Value", &n.Value)
+        applyField(a, n, "Iterable", &n.Iterable)
+        applyField(a, n, "Body", &n.Body)
+
+    case *BreakStatement:
+        applyField(a, n, "Label", &n.Label)
+
+    case *ContinueStatement:
+        applyField(a, n, "Label", &n.Label)
+
+    case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+        // Leaves
+
+    case *PrefixExpression:
+        applyField(a, n, "Right", &n.Right)
+
+    case *InfixExpression:
+        applyField(a, n, "Left", &n.Left)
+        applyField(a, n, "Right", &n.Right)
+
+    case *IfExpression:
+        applyField(a, n, "Condition", &n.Condition)
+        applyField(a, n, "Consequence", &n.Consequence)
+        applyField(a, n, "Alternative", &n.Alternative)
+
+    case *FunctionLiteral:
+        applyList(a, n, "Parameters", &n.Parameters)
+        applyField(a, n, "Body", &n.Body)
+
+    case *CallExpression:
+        applyField(a, n, "Function", &n.Function)
+        applyList(a, n, "Arguments", &n.Arguments)
+
+    case *ArrayLiteral:
+        applyList(a, n, "Elements", &n.Elements)
+
+    case *IndexExpression:
+        applyField(a, n, "Left", &n.Left)
+        applyField(a, n, "Index", &n.Index)
+
+    case *SliceExpression:
+        applyField(a, n, "Left", &n.Left)
+        applyField(a, n, "Low", &n.Low)
+        applyField(a, n, "High", &n.High)
+
+    case *HashLiteral:
+        a.applyPairs(n)
+
+    case *AssignExpression:
+        applyField(a, n, "Target", &n.Target)
+        applyField(a, n, "Value", &n.Value)
+
+    default:
+        panic(fmt.Sprintf("ast.Apply: unexpected node type %T", n))
+    }
+
+    if a.post != nil && !a.post(c) {
+        panic(abort)
+    }
+}
+
+func applyField[T Node](a *application, parent Node, name string, field *T) {
+    if isNil(*field) {
+        return
+    }
+    a.apply(&fieldSlot[T]{parentNode: parent, fieldName: name, field: field}, *field)
+}
+
+// Nested lists save and restore the iterator of the enclosing one, so the
+// cursors of the enclosing list keep pointing at the right element
+func applyList[T Node](a *application, parent Node, name string, list *[]T) {
+    saved := a.iter
+    a.iter = iterator{}
+
+    for a.iter.index < len(*list) {
+        a.iter.step = 1
+        if n := (*list)[a.iter.index]; !isNil(n) {
+            a.apply(&listSlot[T]{parentNode: parent, fieldName: name, list: list, iter: &a.iter}, n)
+        }
+        a.iter.index += a.iter.step
+    }
+
+    a.iter = saved
+}
+
+func (a *application) applyPairs(hash *HashLiteral) {
+    saved := a.iter
+    a.iter = iterator{}
+
+    for a.iter.index < len(hash.Pairs) {
+        a.iter.step = 1
+
+        pair := hash.Pairs[a.iter.index]
+        if pair.Key != nil {
+            a.apply(&pairSlot{hash: hash, key: true, iter: &a.iter}, pair.Key)
+        }
+
+        // Unless the key removed the pair
+        if a.iter.step == 1 && hash.Pairs[a.iter.index].Value != nil {
+            a.apply(&pairSlot{hash: hash, iter: &a.iter}, hash.Pairs[a.iter.index].Value)
+        }
+        a.iter.index += a.iter.step
+    }
+
+    a.iter = saved
+}
+
+func isNil[T Node](n T) bool {
+    var zero T
+    return any(n) == any(zero)
+}
+
+// Converts a replacement to the type of the field it goes into
+func convert[T Node](n Node, parent Node, name string) T {
+    var zero T
+    if n == nil {
+        return zero
+    }
+
+    t, ok := n.(T)
+    if !ok {
+        panic(fmt.Sprintf("ast.Apply: cannot put %T into %T.%s", n, parent, name))
+    }
+    return t
+}
+
+// Where a node sits in its parent
+type slot interface {
+    parent() Node
+    name() string
+    index() int
+    set(n Node)
+    delete()
+    insertBefore(n Node)
+    insertAfter(n Node)
+}
+
+type fieldSlot[T Node] struct {
+    parentNode Node
+    fieldName string
+    field *T
+}
+
+func (s *fieldSlot[T]) parent() Node { return s.parentNode }
+func (s *fieldSlot[T]) name() string { return s.fieldName }
+func (s *fieldSlot[T]) index() int { return -1 }
+func (s *fieldSlot[T]) set(n Node) { *s.field = convert[T](n, s.parentNode, s.fieldName) }
+func (s *fieldSlot[T]) delete() { s.notInList("Delete") }
+func (s *fieldSlot[T]) insertBefore(n Node) { s.notInList("InsertBefore") }
+func (s *fieldSlot[T]) insertAfter(n Node) { s.notInList("InsertAfter") }
+
+func (s *fieldSlot[T]) notInList(method string) {
+    panic(fmt.Sprintf("ast.Apply: %s of %T.%s, which is not in a list", method, s.parentNode, s.fieldName))
+}
+
+type listSlot[T Node] struct {
+    parentNode Node
+    fieldName string
+    list *[]T
+    iter *iterator
+}
+
+func (s *listSlot[T]) parent() Node { return s.parentNode }
+func (s *listSlot[T]) name() string { return s.fieldName }
+func (s *listSlot[T]) index() int { return s.iter.index }
+
+func (s *listSlot[T]) set(n Node) {
+    (*s.list)[s.iter.index] = convert[T](n, s.parentNode, s.fieldName)
+}
+
+func (s *listSlot[T]) delete() {
+    i := s.iter.index
+    *s.list = append((*s.list)[:i], (*s.list)[i+1:]...)
+    s.iter.step -= 1
+}
+
+func (s *listSlot[T]) insertBefore(n Node) {
+    s.insert(s.iter.index, n)
+    s.iter.index += 1
+}
+
+func (s *listSlot[T]) insertAfter(n Node) {
+    s.insert(s.iter.index+1, n)
+    s.iter.step += 1
+}
+
+func (s *listSlot[T]) insert(i int, n Node) {
+    el := convert[T](n, s.parentNode, s.fieldName)
+    if isNil(el) {
+        panic(fmt.Sprintf("ast.Apply: cannot insert nil into %T.%s", s.parentNode, s.fieldName))
+    }
+
+    *s.list = append(*s.list, el)
+    copy((*s.list)[i+1:], (*s.list)[i:])
+    (*s.list)[i] = el
+}
+
+// The key or value of a hash literal pair
+type pairSlot struct {
+    hash *HashLiteral
+    key bool
+    iter *iterator
+}
+
+func (s *pairSlot) parent() Node { return s.hash }
+func (s *pairSlot) index() int { return s.iter.index }
+
+func (s *pairSlot) name() string {
+    if s.key {
+        return "Key"
+    }
+    return "Value"
+}
+
+func (s *pairSlot) set(n Node) {
+    exp := convert[Expression](n, s.hash, s.name())
+    if s.key {
+        s.hash.Pairs[s.iter.index].Key = exp
+    } else {
+        s.hash.Pairs[s.iter.index].Value = exp
+    }
+}
+
+func (s *pairSlot) delete() {
+    i := s.iter.index
+    s.hash.Pairs = append(s.hash.Pairs[:i], s.hash.Pairs[i+1:]...)
+    s.iter.step -= 1
+}
+
+func (s *pairSlot) insertBefore(n Node) { s.cannotInsert() }
+func (s *pairSlot) insertAfter(n Node) { s.cannotInsert() }
+
+func (s *pairSlot) cannotInsert() {
+    panic("ast.Apply: cannot insert into a hash literal, replace it instead")
+}
+
+// Gives every token of n that has no position the range pos to end
+func inheritPositions(n Node, pos, end token.Position) {
+    if !pos.IsValid() {
+        return
+    }
+
+    Inspect(n, func(node Node) bool {
+        for _, tok := range nodeTokens(node) {
+            if !tok.Pos.IsValid() {
+                tok.Pos = pos
+                tok.End = end
+            }
+        }
+        return true
+    })
+}
+
+func nodeTokens(n Node) []*token.Token {
+    switch n := n.(type) {
+    case *LetStatement:
+        return []*token.Token{&n.Token}
+    case *ReturnStatement:
+        return []*token.Token{&n.Token}
+    case *ExpressionStatement:
+        return []*token.Token{&n.Token}
+    case *BlockStatement:
+        return []*token.Token{&n.Token, &n.RBrace}
+    case *WhileStatement:
+        return []*token.Token{&n.Token}
+    case *ForStatement:
+        return []*token.Token{&n.Token}
+    case *BreakStatement:
+        return []*token.Token{&n.Token}
+    case *ContinueStatement:
+        return []*token.Token{&n.Token}
+    case *Identifier:
+        return []*token.Token{&n.Token}
+    case *IntegerLiteral:
+        return []*token.Token{&n.Token}
+    case *FloatLiteral:
+        return []*token.Token{&n.Token}
+    case *StringLiteral:
+        return []*token.Token{&n.Token}
+    case *Boolean:
+        return []*token.Token{&n.Token}
+    case *PrefixExpression:
+        return []*token.Token{&n.Token}
+    case *InfixExpression:
+        return []*token.Token{&n.Token}
+    case *IfExpression:
+        return []*token.Token{&n.Token}
+    case *FunctionLiteral:
+        return []*token.Token{&n.Token}
+    case *CallExpression:
+        return []*token.Token{&n.Token, &n.RParen}
+    case *ArrayLiteral:
+        return []*token.Token{&n.Token, &n.RBracket}
+    case *IndexExpression:
+        return []*token.Token{&n.Token, &n.RBracket}
+    case *SliceExpression:
+        return []*token.Token{&n.Token, &n.RBracket}
+    case *HashLiteral:
+        return []*token.Token{&n.Token, &n.RBrace}
+    case *AssignExpression:
+        return []*token.Token{&n.Token}
+    }
+    return nil
+}
@@ -0,0 +1,252 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkeylang/ast"
+	"monkeylang/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Folds additions and multiplications of integer literals
+func fold(node ast.Node) ast.Node {
+    infix, ok := node.(*ast.InfixExpression)
+    if !ok {
+        return node
+    }
+
+    left, ok := infix.Left.(*ast.IntegerLiteral)
+    if !ok {
+        return node
+    }
+    right, ok := infix.Right.(*ast.IntegerLiteral)
+    if !ok {
+        return node
+    }
+
+    var value int64
+    switch infix.Operator {
+    case "+":
+        value = left.Value + right.Value
+    case "*":
+        value = left.Value * right.Value
+    default:
+        return node
+    }
+
+    literal := strconv.FormatInt(value, 10)
+    return &ast.IntegerLiteral{Token: token.Token{Type: token.Int, Literal: literal}, Value: value}
+}
+
+func TestModifyConstantFolding(t *testing.T) {
+    program := parse(t, "let x = 1 + 2 * 3;\nf(4 + x, 2 * 2 - 1)")
+
+    ast.Modify(program, fold)
+
+    if got := program.String(); got != "let x = 7;f((4 + x), (4 - 1))" {
+        t.Fatalf("wrong result got %q", got)
+    }
+
+    // The folded literal covers the source of the expression it replaced
+    let := program.Statements[0].(*ast.LetStatement)
+    if pos, end := let.Value.Pos().String(), let.Value.End().String(); pos != "1:9" || end != "1:18" {
+        t.Errorf("expected the literal at 1:9-1:18 got %s-%s", pos, end)
+    }
+
+    call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+    four := call.Arguments[1].(*ast.InfixExpression).Left
+    if pos, end := four.Pos().String(), four.End().String(); pos != "2:10" || end != "2:15" {
+        t.Errorf("expected the literal at 2:10-2:15 got %s-%s", pos, end)
+    }
+}
+
+func TestModifyEveryField(t *testing.T) {
+    // Every 1 becomes a 2, wherever it is
+    inputs := []string{
+        "1",
+        "-1",
+        "1 + 1",
+        "let x = 1;",
+        "return 1;",
+        "if 1 { 1 } else { 1 }",
+        "fn(a) { 1 }",
+        "f(1, 1)",
+        "[1, 1]",
+        "{1: 1}",
+        "a[1]",
+        "a[1:1]",
+        "a[1] = 1",
+        "while 1 { 1 }",
+        "for k, v in 1 { 1 }",
+    }
+
+    for _, input := range inputs {
+        program := parse(t, input)
+        expected := parse(t, strings.ReplaceAll(input, "1", "2")).String()
+
+        ast.Modify(program, func(n ast.Node) ast.Node {
+            if integer, ok := n.(*ast.IntegerLiteral); ok && integer.Value == 1 {
+                return &ast.IntegerLiteral{Token: token.Token{Type: token.Int, Literal: "2"}, Value: 2}
+            }
+            return n
+        })
+
+        if got := program.String(); got != expected {
+            t.Errorf("%q expected %q got %q", input, expected, got)
+        }
+    }
+}
+
+func TestModifyDelete(t *testing.T) {
+    program := parse(t, "debug(1); let a = fn(x, unused) { debug(2); debug(3); x }; debug(4); a([unused, 1], {unused: 1, 2: 3})")
+
+    ast.Modify(program, func(n ast.Node) ast.Node {
+        if stmt, ok := n.(*ast.ExpressionStatement); ok {
+            if call, ok := stmt.Expression.(*ast.CallExpression); ok && call.Function.String() == "debug" {
+                return nil
+            }
+        }
+        if ident, ok := n.(*ast.Identifier); ok && ident.Value == "unused" {
+            return nil
+        }
+        return n
+    })
+
+    if got := program.String(); got != "let a = fn(x) { x };a([1], {2: 3})" {
+        t.Errorf("wrong result got %q", got)
+    }
+}
+
+func TestModifyRoot(t *testing.T) {
+    program := parse(t, "1 + 2")
+    exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+    if got := ast.Modify(exp, fold); got.String() != "3" {
+        t.Errorf("expected the root to be replaced got %s", got)
+    }
+}
+
+func TestApplyInsert(t *testing.T) {
+    program := parse(t, "let a = 1;\nlet b = fn() { let c = 2; c };")
+
+    call := func(name string) *ast.ExpressionStatement {
+        ident := &ast.Identifier{Token: token.Token{Type: token.Ident, Literal: name}, Value: name}
+        return &ast.ExpressionStatement{Expression: &ast.CallExpression{Function: ident}}
+    }
+
+    visited := 0
+    ast.Apply(program, func(c *ast.Cursor) bool {
+        visited += 1
+        if let, ok := c.Node().(*ast.LetStatement); ok {
+            c.InsertBefore(call("before_" + let.Name.Value))
+            c.InsertAfter(call("after_" + let.Name.Value))
+        }
+        return true
+    }, nil)
+
+    expected := "before_a()let a = 1;after_a()before_b()let b = fn() { before_c()let c = 2;after_c()c };after_b()"
+    if got := program.String(); got != expected {
+        t.Fatalf("wrong result\nexpected %q\ngot      %q", expected, got)
+    }
+
+    // Inserted nodes are not visited
+    if visited != 13 {
+        t.Errorf("expected 13 visited nodes got %d", visited)
+    }
+
+    // Inserted nodes sit at the start or the end of their neighbour
+    before, after := program.Statements[0], program.Statements[2]
+    if before.Pos().String() != "1:1" || before.End().String() != "1:1" {
+        t.Errorf("expected the insertion at 1:1 got %s-%s", before.Pos(), before.End())
+    }
+    if after.Pos().String() != "1:10" || after.End().String() != "1:10" {
+        t.Errorf("expected the insertion at 1:10 got %s-%s", after.Pos(), after.End())
+    }
+}
+
+func TestApplyCursor(t *testing.T) {
+    program := parse(t, "f(a, b[c]); {d: e}")
+
+    var got []string
+    ast.Apply(program, func(c *ast.Cursor) bool {
+        if ident, ok := c.Node().(*ast.Identifier); ok {
+            got = append(got, fmt.Sprintf("%s %T.%s %d", ident.Value, c.Parent(), c.Name(), c.Index()))
+        }
+        if c.Parent() == nil && c.Node() != program {
+            t.Errorf("only the root has no parent got %T", c.Node())
+        }
+        return true
+    }, nil)
+
+    expected := []string{
+        "f *ast.CallExpression.Function -1",
+        "a *ast.CallExpression.Arguments 0",
+        "b *ast.IndexExpression.Left -1",
+        "c *ast.IndexExpression.Index -1",
+        "d *ast.HashLiteral.Key 0",
+        "e *ast.HashLiteral.Value 0",
+    }
+    if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+        t.Errorf("wrong cursors\nexpected %q\ngot      %q", expected, got)
+    }
+}
+
+func TestApplyPruneAndAbort(t *testing.T) {
+    program := parse(t, "a; fn() { b }; c; d")
+
+    var names []string
+    ast.Apply(program, func(c *ast.Cursor) bool {
+        _, isFunction := c.Node().(*ast.FunctionLiteral)
+        return !isFunction
+    }, func(c *ast.Cursor) bool {
+        if ident, ok := c.Node().(*ast.Identifier); ok {
+            names = append(names, ident.Value)
+            return ident.Value != "c"
+        }
+        return true
+    })
+
+    if got := strings.Join(names, " "); got != "a c" {
+        t.Errorf("expected a c got %q", got)
+    }
+}
+
+func TestApplyPanics(t *testing.T) {
+    tests := []struct {
+        input string
+        apply func(c *ast.Cursor)
+        expected string
+    } {
+        {"1 + 2", func(c *ast.Cursor) {
+            if _, ok := c.Node().(*ast.IntegerLiteral); ok {
+                c.Delete()
+            }
+        }, "ast.Apply: Delete of *ast.InfixExpression.Left, which is not in a list"},
+        {"1 + 2", func(c *ast.Cursor) {
+            if _, ok := c.Node().(*ast.IntegerLiteral); ok {
+                c.Replace(&ast.BlockStatement{})
+            }
+        }, "ast.Apply: cannot put *ast.BlockStatement into *ast.InfixExpression.Left"},
+        {"{1: 2}", func(c *ast.Cursor) {
+            if _, ok := c.Node().(*ast.IntegerLiteral); ok {
+                c.InsertAfter(c.Node())
+            }
+        }, "ast.Apply: cannot insert into a hash literal, replace it instead"},
+    }
+
+    for _, tt := range tests {
+        func() {
+            defer func() {
+                if r := recover(); r != tt.expected {
+                    t.Errorf("%q expected panic %q got %v", tt.input, tt.expected, r)
+                }
+            }()
+
+            ast.Apply(parse(t, tt.input), func(c *ast.Cursor) bool {
+                tt.apply(c)
+                return true
+            }, nil)
+        }()
+    }
+}
@@ -0,0 +1,156 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called for every node met by Walk. If it
+// returns a non-nil visitor w, Walk visits each child of the node with w and
+// then calls w.Visit(nil).
+type Visitor interface {
+    Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, children in source order.
+// Missing children, e.g. the alternative of an if without an else or the
+// parts a failed parse left out, are skipped.
+func Walk(v Visitor, node Node) {
+    if v = v.Visit(node); v == nil {
+        return
+    }
+
+    switch n := node.(type) {
+    case *Program:
+        walkStatements(v, n.Statements)
+
+    case *LetStatement:
+        walkIdent(v, n.Name)
+        walkExpr(v, n.Value)
+
+    case *ReturnStatement:
+        walkExpr(v, n.ReturnValue)
+
+    case *ExpressionStatement:
+        walkExpr(v, n.Expression)
+
+    case *BlockStatement:
+        walkStatements(v, n.Statements)
+
+    case *WhileStatement:
+        walkIdent(v, n.Label)
+        walkExpr(v, n.Condition)
+        walkBlock(v, n.Body)
+
+    case *ForStatement:
+        walkIdent(v, n.Label)
+        walkIdent(v, n.Key)
+        walkIdent(v, n.Value)
+        walkExpr(v, n.Iterable)
+        walkBlock(v, n.Body)
+
+    case *BreakStatement:
+        walkIdent(v, n.Label)
+
+    case *ContinueStatement:
+        walkIdent(v, n.Label)
+
+    case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+        // Leaves
+
+    case *PrefixExpression:
+        walkExpr(v, n.Right)
+
+    case *InfixExpression:
+        walkExpr(v, n.Left)
+        walkExpr(v, n.Right)
+
+    case *IfExpression:
+        walkExpr(v, n.Condition)
+        walkBlock(v, n.Consequence)
+        walkBlock(v, n.Alternative)
+
+    case *FunctionLiteral:
+        for _, param := range n.Parameters {
+            walkIdent(v, param)
+        }
+        walkBlock(v, n.Body)
+
+    case *CallExpression:
+        walkExpr(v, n.Function)
+        walkExpressions(v, n.Arguments)
+
+    case *ArrayLiteral:
+        walkExpressions(v, n.Elements)
+
+    case *IndexExpression:
+        walkExpr(v, n.Left)
+        walkExpr(v, n.Index)
+
+    case *SliceExpression:
+        walkExpr(v, n.Left)
+        walkExpr(v, n.Low)
+        walkExpr(v, n.High)
+
+    case *HashLiteral:
+        for _, pair := range n.Pairs {
+            walkExpr(v, pair.Key)
+            walkExpr(v, pair.Value)
+        }
+
+    case *AssignExpression:
+        walkExpr(v, n.Target)
+        walkExpr(v, n.Value)
+
+    default:
+        panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+    }
+
+    v.Visit(nil)
+}
+
+// The typed helpers keep nil pointers from turning into non-nil interfaces
+func walkIdent(v Visitor, ident *Identifier) {
+    if ident != nil {
+        Walk(v, ident)
+    }
+}
+
+func walkBlock(v Visitor, block *BlockStatement) {
+    if block != nil {
+        Walk(v, block)
+    }
+}
+
+func walkExpr(v Visitor, exp Expression) {
+    if exp != nil {
+        Walk(v, exp)
+    }
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+    for _, exp := range list {
+        walkExpr(v, exp)
+    }
+}
+
+func walkStatements(v Visitor, list []Statement) {
+    for _, stmt := range list {
+        if stmt != nil {
+            Walk(v, stmt)
+        }
+    }
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+    if f(node) {
+        return f
+    }
+    return nil
+}
+
+// Inspect traverses an AST like Walk, calling f for every node. If f returns
+// true the children of the node are inspected too, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+    Walk(inspector(f), node)
+}
@@ -0,0 +1,140 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkeylang/ast"
+	"monkeylang/lexer"
+	"monkeylang/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+
+    if errors := p.Errors(); len(errors) != 0 {
+        t.Fatalf("%q has parse errors %v", input, errors)
+    }
+    return program
+}
+
+// Describes every node Inspect meets, and ")" when it leaves one
+func trace(node ast.Node) string {
+    var out []string
+
+    ast.Inspect(node, func(n ast.Node) bool {
+        if n == nil {
+            out = append(out, ")")
+            return false
+        }
+        out = append(out, fmt.Sprintf("%T", n)[len("*ast."):])
+        return true
+    })
+
+    return strings.Join(out, " ")
+}
+
+func TestInspectPreOrder(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"let x = -a + b;",
+            "Program LetStatement Identifier ) InfixExpression PrefixExpression Identifier ) ) Identifier ) ) ) )"},
+        {"return;", "Program ReturnStatement ) )"},
+        {"f(1, 2.5)",
+            "Program ExpressionStatement CallExpression Identifier ) IntegerLiteral ) FloatLiteral ) ) ) )"},
+        {"if (true) { x } else { }",
+            "Program ExpressionStatement IfExpression Boolean ) BlockStatement ExpressionStatement Identifier ) ) ) BlockStatement ) ) ) )"},
+        {"fn(a) { }", "Program ExpressionStatement FunctionLiteral Identifier ) BlockStatement ) ) ) )"},
+        {`{"k": [1]}[0:1]`,
+            "Program ExpressionStatement SliceExpression HashLiteral StringLiteral ) ArrayLiteral IntegerLiteral ) ) ) IntegerLiteral ) IntegerLiteral ) ) ) )"},
+        {"a[0] = 1",
+            "Program ExpressionStatement AssignExpression IndexExpression Identifier ) IntegerLiteral ) ) IntegerLiteral ) ) ) )"},
+        {"l: for (k, v in h) { while x { break l; continue } }",
+            "Program ForStatement Identifier ) Identifier ) Identifier ) Identifier ) BlockStatement WhileStatement Identifier ) BlockStatement BreakStatement Identifier ) ) ContinueStatement ) ) ) ) ) )"},
+    }
+
+    for _, tt := range tests {
+        got := trace(parse(t, tt.input))
+        if got != tt.expected {
+            t.Errorf("%q\nexpected %s\ngot      %s", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestInspectSourceOrder(t *testing.T) {
+    program := parse(t, "let a = b * (c + d); e[f] = g(h, i);")
+
+    var names []string
+    ast.Inspect(program, func(n ast.Node) bool {
+        if ident, ok := n.(*ast.Identifier); ok {
+            names = append(names, ident.Value)
+        }
+        return true
+    })
+
+    if got := strings.Join(names, ""); got != "abcdefghi" {
+        t.Errorf("expected identifiers in source order got %q", got)
+    }
+}
+
+func TestInspectPruning(t *testing.T) {
+    program := parse(t, "let f = fn(x) { x + y }; f(z)")
+
+    var names []string
+    ast.Inspect(program, func(n ast.Node) bool {
+        switch n := n.(type) {
+        case *ast.FunctionLiteral:
+            return false
+        case *ast.Identifier:
+            names = append(names, n.Value)
+        }
+        return true
+    })
+
+    if got := strings.Join(names, " "); got != "f f z" {
+        t.Errorf("expected the function body to be skipped got %q", got)
+    }
+}
+
+type depthVisitor struct {
+    depth *int
+    max *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+    if node == nil {
+        *v.depth -= 1
+        return nil
+    }
+
+    *v.depth += 1
+    if *v.depth > *v.max {
+        *v.max = *v.depth
+    }
+    return v
+}
+
+func TestWalkVisitsNilAfterChildren(t *testing.T) {
+    depth, max := 0, 0
+    ast.Walk(depthVisitor{&depth, &max}, parse(t, "1 + (2 * (3 - 4))"))
+
+    if depth != 0 {
+        t.Errorf("expected every node to be left again got depth %d", depth)
+    }
+    // Program, statement and three nested infix expressions above a literal
+    if max != 6 {
+        t.Errorf("expected max depth 6 got %d", max)
+    }
+}
+
+func TestWalkPartialTree(t *testing.T) {
+    // The kind of tree a failed parse leaves behind
+    node := &ast.IfExpression{Condition: &ast.Boolean{Value: true}}
+
+    if got := trace(node); got != "IfExpression Boolean ) )" {
+        t.Errorf("expected missing blocks to be skipped got %q", got)
+    }
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines of context around each change
+const diffContext = 3
+
+// A line of a diff: ' ' kept, '-' removed, '+' added
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// Renders the differences between a and b in unified format, or returns
+// "" if there are none
+func unifiedDiff(filename, a, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+	if !hasChanges(lines) {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", filename, filename)
+
+	// Line numbers in a and b where lines[k] starts
+	aLine, bLine := 1, 1
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			aLine, bLine = aLine+1, bLine+1
+			k += 1
+			continue
+		}
+
+		// A hunk runs until diffContext*2 unchanged lines in a row
+		start := max(k-diffContext, 0)
+		end := k
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end += 1
+				continue
+			}
+			same := end
+			for same < len(lines) && lines[same].op == ' ' {
+				same += 1
+			}
+			if same == len(lines) || same-end > diffContext*2 {
+				end = min(end+diffContext, len(lines))
+				break
+			}
+			end = same
+		}
+
+		aStart, bStart := aLine-(k-start), bLine-(k-start)
+		aCount, bCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				aCount += 1
+			}
+			if l.op != '-' {
+				bCount += 1
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, l := range lines[start:end] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+
+		for _, l := range lines[k:end] {
+			if l.op != '+' {
+				aLine += 1
+			}
+			if l.op != '-' {
+				bLine += 1
+			}
+		}
+		k = end
+	}
+
+	return out.String()
+}
+
+func hasChanges(lines []diffLine) bool {
+	for _, l := range lines {
+		if l.op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares a and b with Myers' algorithm in its linear space form, which
+// splits the problem at the middle of a shortest edit script instead of
+// keeping every step of the search
+func diffLines(a, b []string) []diffLine {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.lines
+}
+
+type differ struct {
+	a, b  []string
+	lines []diffLine
+}
+
+// Appends the edits turning a[a0:a1] into b[b0:b1]
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.lines = append(d.lines, diffLine{' ', d.a[a0]})
+		a0, b0 = a0+1, b0+1
+	}
+
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix += 1
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	switch {
+	case a0 == a1:
+		for _, text := range d.b[b0:b1] {
+			d.lines = append(d.lines, diffLine{'+', text})
+		}
+	case b0 == b1:
+		for _, text := range d.a[a0:a1] {
+			d.lines = append(d.lines, diffLine{'-', text})
+		}
+	default:
+		// Both ends differ, so at least two edits are needed and each
+		// half has fewer than the whole
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for _, text := range d.a[x:u] {
+			d.lines = append(d.lines, diffLine{' ', text})
+		}
+		d.compare(u, a1, v, b1)
+	}
+
+	for _, text := range d.a[a1 : a1+suffix] {
+		d.lines = append(d.lines, diffLine{' ', text})
+	}
+}
+
+// Searches from both corners at once until the paths meet, and returns
+// the run of equal lines, from (x, y) to (u, v), where they do
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+
+	// Furthest x reached on each diagonal k = x - y, forwards and, for the
+	// reversed sequences, backwards. Index k+offset.
+	offset := limit + 1
+	forward := make([]int, 2*limit+3)
+	backward := make([]int, 2*limit+3)
+
+	for steps := 0; steps <= limit; steps++ {
+		for k := -steps; k <= steps; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -steps || k != steps && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+
+			// The backward search has done steps-1 steps
+			if c := delta - k; odd && c >= -(steps-1) && c <= steps-1 && x+backward[offset+c] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+
+		for c := -steps; c <= steps; c += 2 {
+			x := backward[offset+c-1] + 1
+			if c == -steps || c != steps && backward[offset+c-1] < backward[offset+c+1] {
+				x = backward[offset+c+1]
+			}
+			y := x - c
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x, y = x+1, y+1
+			}
+			backward[offset+c] = x
+
+			if k := delta - c; !odd && k >= -steps && k <= steps && x+forward[offset+k] >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY
+			}
+		}
+	}
+
+	panic("unreachable: the searches always meet")
+}
+
+// An empty range is numbered by the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start -= 1
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// The marker diff(1) prints after a last line that has no newline
+const noNewline = "\\ No newline at end of file"
+
+// A last line without a newline carries the marker, so it differs from the
+// same text with one and the marker is printed on the next line
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += "\n" + noNewline
+	}
+	return lines
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Lines that repeat every 78, so the diff has many candidate matches
+func manyLines(from, to int) string {
+	var out strings.Builder
+	for i := from; i <= to; i++ {
+		out.WriteString(strings.Repeat("x", i%3) + string(rune('a'+i%26)) + "\n")
+	}
+	return out.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	ten := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{"empty", "", "", ""},
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"from nothing", "", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to nothing", "a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"insert at start", ten, "0\n" + ten, "@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n"},
+		{"delete at end", ten, strings.TrimSuffix(ten, "10\n"), "@@ -7,4 +7,3 @@\n 7\n 8\n 9\n-10\n"},
+		{"change in the middle", ten, strings.Replace(ten, "5\n", "five\n", 1),
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"},
+		// Six unchanged lines between two changes still make one hunk
+		{"merged hunks", ten, strings.NewReplacer("2\n", "two\n", "9\n", "nine\n").Replace(ten),
+			"@@ -1,10 +1,10 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n"},
+		// Seven do not
+		{"separate hunks", ten, strings.NewReplacer("1\n", "one\n", "9\n", "nine\n").Replace(ten),
+			"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -6,5 +6,5 @@\n 6\n 7\n 8\n-9\n+nine\n 10\n"},
+		{"newline added", "let x = 1;", "let x = 1;\n",
+			"@@ -1 +1 @@\n-let x = 1;\n\\ No newline at end of file\n+let x = 1;\n"},
+		{"newline removed", "a\nb\n", "a\nb",
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"},
+		{"unchanged without newline", "a\nb", "a\nc\nb", "@@ -1,2 +1,3 @@\n a\n+c\n b\n\\ No newline at end of file\n"},
+	}
+
+	for _, tt := range tests {
+		expected := tt.expected
+		if expected != "" {
+			expected = "--- f.orig\n+++ f\n" + expected
+		}
+
+		if got := unifiedDiff("f", tt.a, tt.b); got != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, expected, got)
+		}
+	}
+}
+
+// The length of the longest common subsequence, the slow way
+func lcsLength(a, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		prev := 0
+		for j := range b {
+			cur := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = prev + 1
+			} else {
+				row[j+1] = max(row[j+1], row[j])
+			}
+			prev = cur
+		}
+	}
+	return row[len(b)]
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d"}
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = words[r.Intn(len(words))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 5000; i++ {
+		a, b := random(), random()
+		lines := diffLines(a, b)
+
+		old, new, kept := []string{}, []string{}, 0
+		for _, l := range lines {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+			if l.op != '-' {
+				new = append(new, l.text)
+			}
+			if l.op == ' ' {
+				kept += 1
+			}
+		}
+
+		if strings.Join(old, ",") != strings.Join(a, ",") || strings.Join(new, ",") != strings.Join(b, ",") {
+			t.Fatalf("%q -> %q: diff %v does not rebuild both sides", a, b, lines)
+		}
+		if kept != lcsLength(a, b) {
+			t.Fatalf("%q -> %q: kept %d lines, the most is %d", a, b, kept, lcsLength(a, b))
+		}
+	}
+}
+
+func TestDiffLargeFile(t *testing.T) {
+	a := manyLines(0, 20000)
+	b := strings.Replace(a, "xxb\n", "changed\n", 1)
+
+	if got := strings.Count(unifiedDiff("f", a, b), "\n-"); got != 1 {
+		t.Errorf("expected one removed line got %d", got)
+	}
+}
@@ -0,0 +1,869 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"monkeylang/ast"
+	"monkeylang/object"
+	"strings"
+)
+
+// There is only ever one true, false and null
+var (
+    NULL = &object.Null{}
+    TRUE = &object.Boolean{Value: true}
+    FALSE = &object.Boolean{Value: false}
+)
+
+func Eval(node ast.Node, env *object.Environment) object.Object {
+    switch node := node.(type) {
+
+    // Statements
+    case *ast.Program:
+        return evalProgram(node, env)
+
+    case *ast.BlockStatement:
+        return evalBlockStatement(node, env)
+
+    case *ast.ExpressionStatement:
+        return Eval(node.Expression, env)
+
+    case *ast.ReturnStatement:
+        if node.ReturnValue == nil {
+            return &object.ReturnValue{Value: NULL}
+        }
+        val := Eval(node.ReturnValue, env)
+        if isError(val) {
+            return val
+        }
+        return &object.ReturnValue{Value: val}
+
+    case *ast.LetStatement:
+        val := Eval(node.Value, env)
+        if isError(val) {
+            return val
+        }
+
+        // The function can refer to itself, since it looks names up in env
+        // when it is called, by which time the binding exists
+        if fn, ok := val.(*object.Function); ok {
+            if _, literal := node.Value.(*ast.FunctionLiteral); literal {
+                fn.Name = node.Name.Value
+            }
+        }
+        env.Set(node.Name.Value, val)
+
+    case *ast.WhileStatement:
+        return evalWhileStatement(node, env)
+
+    case *ast.ForStatement:
+        return evalForStatement(node, env)
+
+    case *ast.BreakStatement:
+        return &object.Break{Label: labelName(node.Label)}
+
+    case *ast.ContinueStatement:
+        return &object.Continue{Label: labelName(node.Label)}
+
+    // Expressions
+    case *ast.IntegerLiteral:
+        return &object.Integer{Value: node.Value}
+
+    case *ast.FloatLiteral:
+        return &object.Float{Value: node.Value}
+
+    case *ast.StringLiteral:
+        return &object.String{Value: node.Value}
+
+    case *ast.Boolean:
+        return nativeBoolToBooleanObject(node.Value)
+
+    case *ast.PrefixExpression:
+        right := Eval(node.Right, env)
+        if isError(right) {
+            return right
+        }
+        return evalPrefixExpression(node.Operator, right)
+
+    case *ast.InfixExpression:
+        if node.Operator == "&&" || node.Operator == "||" {
+            return evalLogicalExpression(node, env)
+        }
+
+        left := Eval(node.Left, env)
+        if isError(left) {
+            return left
+        }
+        right := Eval(node.Right, env)
+        if isError(right) {
+            return right
+        }
+        return evalInfixExpression(node.Operator, left, right)
+
+    case *ast.IfExpression:
+        return evalIfExpression(node, env)
+
+    case *ast.AssignExpression:
+        return evalAssignExpression(node, env)
+
+    case *ast.Identifier:
+        return evalIdentifier(node, env)
+
+    case *ast.FunctionLiteral:
+        return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+
+    case *ast.ArrayLiteral:
+        elements := evalExpressions(node.Elements, env)
+        if len(elements) == 1 && isError(elements[0]) {
+            return elements[0]
+        }
+        return &object.Array{Elements: elements}
+
+    case *ast.HashLiteral:
+        return evalHashLiteral(node, env)
+
+    case *ast.IndexExpression:
+        left := Eval(node.Left, env)
+        if isError(left) {
+            return left
+        }
+        index := Eval(node.Index, env)
+        if isError(index) {
+            return index
+        }
+        return evalIndexExpression(left, index)
+
+    case *ast.SliceExpression:
+        return evalSliceExpression(node, env)
+
+    case *ast.CallExpression:
+        function := Eval(node.Function, env)
+        if isError(function) {
+            return function
+        }
+
+        args := evalExpressions(node.Arguments, env)
+        if len(args) == 1 && isError(args[0]) {
+            return args[0]
+        }
+
+        return applyFunction(function, args)
+    }
+
+    return nil
+}
+
+// Unwraps return values at the top level, errors stop the program
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+    var result object.Object
+
+    for _, statement := range program.Statements {
+        result = Eval(statement, env)
+
+        switch result := result.(type) {
+        case *object.ReturnValue:
+            return result.Value
+        case *object.Error:
+            return result
+        }
+    }
+
+    return result
+}
+
+// Unlike evalProgram the return value stays wrapped, so that it keeps
+// bubbling up through nested blocks until the enclosing function call
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+    var result object.Object
+
+    for _, statement := range block.Statements {
+        result = Eval(statement, env)
+
+        if result != nil {
+            switch result.Type() {
+            case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+                return result
+            }
+        }
+    }
+
+    // Empty blocks and blocks ending in a let still produce a value
+    if result == nil {
+        return NULL
+    }
+
+    return result
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+    if input {
+        return TRUE
+    }
+    return FALSE
+}
+
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+    switch operator {
+    case "!":
+        return evalBangOperatorExpression(right)
+    case "-":
+        return evalMinusPrefixOperatorExpression(right)
+    case "~":
+        integer, ok := right.(*object.Integer)
+        if !ok {
+            return newError("unknown operator: ~%s", right.Type())
+        }
+        return &object.Integer{Value: ^integer.Value}
+    default:
+        return newError("unknown operator: %s%s", operator, right.Type())
+    }
+}
+
+func evalBangOperatorExpression(right object.Object) object.Object {
+    if isTruthy(right) {
+        return FALSE
+    }
+    return TRUE
+}
+
+func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+    switch right := right.(type) {
+    case *object.Integer:
+        return &object.Integer{Value: -right.Value}
+    case *object.Float:
+        return &object.Float{Value: -right.Value}
+    default:
+        return newError("unknown operator: -%s", right.Type())
+    }
+}
+
+func evalInfixExpression(operator string, left, right object.Object) object.Object {
+    switch {
+    case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+        return evalIntegerInfixExpression(operator, left, right)
+    case isNumber(left) && isNumber(right):
+        return evalFloatInfixExpression(operator, left, right)
+    case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+        return evalStringInfixExpression(operator, left, right)
+    case left.Type() != right.Type():
+        return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+    // Booleans and null are singletons, so pointer comparison is enough
+    case operator == "==":
+        return nativeBoolToBooleanObject(left == right)
+    case operator == "!=":
+        return nativeBoolToBooleanObject(left != right)
+    default:
+        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+    }
+}
+
+func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := left.(*object.Integer).Value
+    rightVal := right.(*object.Integer).Value
+
+    switch operator {
+    case "+":
+        return &object.Integer{Value: leftVal + rightVal}
+    case "-":
+        return &object.Integer{Value: leftVal - rightVal}
+    case "*":
+        return &object.Integer{Value: leftVal * rightVal}
+    case "/":
+        if rightVal == 0 {
+            return newError("division by zero")
+        }
+        return &object.Integer{Value: leftVal / rightVal}
+    case "%":
+        if rightVal == 0 {
+            return newError("division by zero")
+        }
+        return &object.Integer{Value: leftVal % rightVal}
+    case "**":
+        if rightVal < 0 {
+            return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+        }
+        return &object.Integer{Value: intPow(leftVal, rightVal)}
+    case "&":
+        return &object.Integer{Value: leftVal & rightVal}
+    case "|":
+        return &object.Integer{Value: leftVal | rightVal}
+    case "^":
+        return &object.Integer{Value: leftVal ^ rightVal}
+    case "<<", ">>":
+        if rightVal < 0 {
+            return newError("negative shift count: %d", rightVal)
+        }
+        if operator == "<<" {
+            return &object.Integer{Value: leftVal << uint64(rightVal)}
+        }
+        return &object.Integer{Value: leftVal >> uint64(rightVal)}
+    case "<":
+        return nativeBoolToBooleanObject(leftVal < rightVal)
+    case ">":
+        return nativeBoolToBooleanObject(leftVal > rightVal)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal <= rightVal)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal >= rightVal)
+    case "==":
+        return nativeBoolToBooleanObject(leftVal == rightVal)
+    case "!=":
+        return nativeBoolToBooleanObject(leftVal != rightVal)
+    default:
+        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+    }
+}
+
+// Mixed integer and float arithmetic is done on floats
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := toFloat(left)
+    rightVal := toFloat(right)
+
+    switch operator {
+    case "+":
+        return &object.Float{Value: leftVal + rightVal}
+    case "-":
+        return &object.Float{Value: leftVal - rightVal}
+    case "*":
+        return &object.Float{Value: leftVal * rightVal}
+    case "/":
+        return &object.Float{Value: leftVal / rightVal}
+    case "%":
+        return &object.Float{Value: math.Mod(leftVal, rightVal)}
+    case "**":
+        return &object.Float{Value: math.Pow(leftVal, rightVal)}
+    case "<":
+        return nativeBoolToBooleanObject(leftVal < rightVal)
+    case ">":
+        return nativeBoolToBooleanObject(leftVal > rightVal)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal <= rightVal)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal >= rightVal)
+    case "==":
+        return nativeBoolToBooleanObject(leftVal == rightVal)
+    case "!=":
+        return nativeBoolToBooleanObject(leftVal != rightVal)
+    default:
+        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+    }
+}
+
+// Exponentiation by squaring, wrapping around on overflow like the other
+// integer operators
+func intPow(base, exp int64) int64 {
+    result := int64(1)
+    for exp > 0 {
+        if exp&1 == 1 {
+            result *= base
+        }
+        base *= base
+        exp >>= 1
+    }
+    return result
+}
+
+// && and || only evaluate their right side when the left one does not
+// already decide the result, which is always a boolean
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+    left := Eval(node.Left, env)
+    if isError(left) {
+        return left
+    }
+
+    if node.Operator == "&&" && !isTruthy(left) {
+        return FALSE
+    }
+    if node.Operator == "||" && isTruthy(left) {
+        return TRUE
+    }
+
+    right := Eval(node.Right, env)
+    if isError(right) {
+        return right
+    }
+
+    return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+func isNumber(obj object.Object) bool {
+    return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+    if i, ok := obj.(*object.Integer); ok {
+        return float64(i.Value)
+    }
+    return obj.(*object.Float).Value
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := left.(*object.String).Value
+    rightVal := right.(*object.String).Value
+
+    switch operator {
+    case "+":
+        return &object.String{Value: leftVal + rightVal}
+    case "<":
+        return nativeBoolToBooleanObject(leftVal < rightVal)
+    case ">":
+        return nativeBoolToBooleanObject(leftVal > rightVal)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal <= rightVal)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal >= rightVal)
+    case "==":
+        return nativeBoolToBooleanObject(leftVal == rightVal)
+    case "!=":
+        return nativeBoolToBooleanObject(leftVal != rightVal)
+    default:
+        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+    }
+}
+
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+    condition := Eval(ie.Condition, env)
+    if isError(condition) {
+        return condition
+    }
+
+    if isTruthy(condition) {
+        return Eval(ie.Consequence, env)
+    } else if ie.Alternative != nil {
+        return Eval(ie.Alternative, env)
+    }
+
+    return NULL
+}
+
+// Assignment updates an existing binding or an element of an array or hash
+// in place, and evaluates to the assigned value. Compound operators like +=
+// combine the old value and the new one with the matching infix operator.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+    switch target := node.Target.(type) {
+    case *ast.Identifier:
+        var current object.Object
+        if node.Operator != "=" {
+            current = evalIdentifier(target, env)
+            if isError(current) {
+                return current
+            }
+        }
+
+        val := evalAssignedValue(node, current, env)
+        if isError(val) {
+            return val
+        }
+
+        if !env.Assign(target.Value, val) {
+            return newError("identifier not found: " + target.Value)
+        }
+        return val
+
+    case *ast.IndexExpression:
+        left := Eval(target.Left, env)
+        if isError(left) {
+            return left
+        }
+        index := Eval(target.Index, env)
+        if isError(index) {
+            return index
+        }
+
+        var current object.Object
+        if node.Operator != "=" {
+            current = evalIndexExpression(left, index)
+            if isError(current) {
+                return current
+            }
+        }
+
+        val := evalAssignedValue(node, current, env)
+        if isError(val) {
+            return val
+        }
+
+        return assignIndex(left, index, val)
+
+    default:
+        return newError("cannot assign to %s", node.Target.String())
+    }
+}
+
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+    val := Eval(node.Value, env)
+    if isError(val) || node.Operator == "=" {
+        return val
+    }
+
+    operator := strings.TrimSuffix(node.Operator, "=")
+    return evalInfixExpression(operator, current, val)
+}
+
+func assignIndex(left, index, val object.Object) object.Object {
+    switch left := left.(type) {
+    case *object.Array:
+        integer, ok := index.(*object.Integer)
+        if !ok {
+            return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+        }
+
+        i, ok := normalizeIndex(integer.Value, len(left.Elements))
+        if !ok {
+            return newError("index out of range: %d with length %d", integer.Value, len(left.Elements))
+        }
+        left.Elements[i] = val
+
+    case *object.Hash:
+        key, ok := index.(object.Hashable)
+        if !ok {
+            return newError("unusable as hash key: %s", index.Type())
+        }
+        left.Set(key, val)
+
+    default:
+        return newError("index assignment not supported: %s", left.Type())
+    }
+
+    return val
+}
+
+// Loops are statements, like let they produce no value
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+    for {
+        condition := Eval(node.Condition, env)
+        if isError(condition) {
+            return condition
+        }
+        if !isTruthy(condition) {
+            return nil
+        }
+
+        result := Eval(node.Body, env)
+        if val, stop := loopControl(result, node.Label); stop {
+            return val
+        }
+    }
+}
+
+// Every pass gets its own environment, so closures created in the body
+// capture that pass's loop variables
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+    iterable := Eval(node.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    keys, values, ok := iterationPairs(iterable)
+    if !ok {
+        return newError("cannot iterate over %s", iterable.Type())
+    }
+
+    // A single variable takes the keys of a hash
+    if node.Key == nil && iterable.Type() == object.HASH_OBJ {
+        values = keys
+    }
+
+    for i := range values {
+        loopEnv := object.NewEnclosedEnvironment(env)
+        if node.Key != nil {
+            loopEnv.Set(node.Key.Value, keys[i])
+        }
+        loopEnv.Set(node.Value.Value, values[i])
+
+        result := Eval(node.Body, loopEnv)
+        if val, stop := loopControl(result, node.Label); stop {
+            return val
+        }
+    }
+
+    return nil
+}
+
+// Arrays and strings pair indices with elements, hashes keys with values.
+// The pairs are taken up front, changes made by the loop body are not seen.
+func iterationPairs(iterable object.Object) ([]object.Object, []object.Object, bool) {
+    var keys, values []object.Object
+
+    switch iterable := iterable.(type) {
+    case *object.Array:
+        for i, el := range iterable.Elements {
+            keys = append(keys, &object.Integer{Value: int64(i)})
+            values = append(values, el)
+        }
+    case *object.String:
+        for i, r := range []rune(iterable.Value) {
+            keys = append(keys, &object.Integer{Value: int64(i)})
+            values = append(values, &object.String{Value: string(r)})
+        }
+    case *object.Hash:
+        for _, key := range iterable.Keys {
+            pair := iterable.Pairs[key]
+            keys = append(keys, pair.Key)
+            values = append(values, pair.Value)
+        }
+    default:
+        return nil, nil, false
+    }
+
+    return keys, values, true
+}
+
+// Decides what a loop does with the result of one pass of its body. A break
+// or continue aimed at an enclosing loop, a return value or an error ends
+// the loop and keeps bubbling up.
+func loopControl(result object.Object, label *ast.Identifier) (object.Object, bool) {
+    switch result := result.(type) {
+    case *object.Break:
+        if result.Label == "" || result.Label == labelName(label) {
+            return nil, true
+        }
+        return result, true
+    case *object.Continue:
+        if result.Label == "" || result.Label == labelName(label) {
+            return nil, false
+        }
+        return result, true
+    case *object.ReturnValue, *object.Error:
+        return result, true
+    }
+
+    return nil, false
+}
+
+func labelName(label *ast.Identifier) string {
+    if label == nil {
+        return ""
+    }
+    return label.Value
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+    val, ok := env.Get(node.Value)
+    if !ok {
+        return newError("identifier not found: " + node.Value)
+    }
+
+    return val
+}
+
+// Negative indices count from the end, anything out of range is null
+func evalIndexExpression(left, index object.Object) object.Object {
+    switch {
+    case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+        elements := left.(*object.Array).Elements
+        i, ok := normalizeIndex(index.(*object.Integer).Value, len(elements))
+        if !ok {
+            return NULL
+        }
+        return elements[i]
+    case left.Type() == object.HASH_OBJ:
+        return evalHashIndexExpression(left, index)
+    case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+        runes := []rune(left.(*object.String).Value)
+        i, ok := normalizeIndex(index.(*object.Integer).Value, len(runes))
+        if !ok {
+            return NULL
+        }
+        return &object.String{Value: string(runes[i])}
+    default:
+        return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+    }
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+    hash := object.NewHash()
+
+    for _, pair := range node.Pairs {
+        key := Eval(pair.Key, env)
+        if isError(key) {
+            return key
+        }
+
+        hashKey, ok := key.(object.Hashable)
+        if !ok {
+            return newError("unusable as hash key: %s", key.Type())
+        }
+
+        value := Eval(pair.Value, env)
+        if isError(value) {
+            return value
+        }
+
+        hash.Set(hashKey, value)
+    }
+
+    return hash
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+    key, ok := index.(object.Hashable)
+    if !ok {
+        return newError("unusable as hash key: %s", index.Type())
+    }
+
+    pair, ok := hash.(*object.Hash).Pairs[key.HashKey()]
+    if !ok {
+        return NULL
+    }
+
+    return pair.Value
+}
+
+func normalizeIndex(i int64, length int) (int64, bool) {
+    if i < 0 {
+        i += int64(length)
+    }
+    return i, i >= 0 && i < int64(length)
+}
+
+// Bounds default to the whole sequence, count from the end when negative
+// and are clamped to it, so slicing never fails on an array or string
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+    left := Eval(node.Left, env)
+    if isError(left) {
+        return left
+    }
+
+    var length int
+    var runes []rune
+    switch left := left.(type) {
+    case *object.Array:
+        length = len(left.Elements)
+    case *object.String:
+        runes = []rune(left.Value)
+        length = len(runes)
+    default:
+        return newError("slice operator not supported: %s", left.Type())
+    }
+
+    low, err := evalSliceBound(node.Low, env, 0, length)
+    if err != nil {
+        return err
+    }
+    high, err := evalSliceBound(node.High, env, length, length)
+    if err != nil {
+        return err
+    }
+    if high < low {
+        high = low
+    }
+
+    if array, ok := left.(*object.Array); ok {
+        elements := make([]object.Object, high-low)
+        copy(elements, array.Elements[low:high])
+        return &object.Array{Elements: elements}
+    }
+    return &object.String{Value: string(runes[low:high])}
+}
+
+func evalSliceBound(exp ast.Expression, env *object.Environment, def, length int) (int, object.Object) {
+    if exp == nil {
+        return def, nil
+    }
+
+    val := Eval(exp, env)
+    if isError(val) {
+        return 0, val
+    }
+
+    integer, ok := val.(*object.Integer)
+    if !ok {
+        return 0, newError("slice bound must be INTEGER, got %s", val.Type())
+    }
+
+    i := integer.Value
+    if i < 0 {
+        i += int64(length)
+    }
+    if i < 0 {
+        i = 0
+    }
+    if i > int64(length) {
+        i = int64(length)
+    }
+
+    return int(i), nil
+}
+
+// Evaluates left to right and stops at the first error, which is then the
+// only element of the result
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+    var result []object.Object
+
+    for _, e := range exps {
+        evaluated := Eval(e, env)
+        if isError(evaluated) {
+            return []object.Object{evaluated}
+        }
+        result = append(result, evaluated)
+    }
+
+    return result
+}
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+    function, ok := fn.(*object.Function)
+    if !ok {
+        return newError("not a function: %s", fn.Type())
+    }
+
+    if len(args) != len(function.Parameters) {
+        if function.Name != "" {
+            return newError("wrong number of arguments to %s: want=%d, got=%d",
+            function.Name, len(function.Parameters), len(args))
+        }
+        return newError("wrong number of arguments: want=%d, got=%d",
+        len(function.Parameters), len(args))
+    }
+
+    extendedEnv := extendFunctionEnv(function, args)
+    evaluated := Eval(function.Body, extendedEnv)
+
+    return unwrapReturnValue(evaluated)
+}
+
+// Parameters live in a new environment enclosed by the one the function
+// was defined in, not the one it is called from
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+    env := object.NewEnclosedEnvironment(fn.Env)
+
+    for i, param := range fn.Parameters {
+        env.Set(param.Value, args[i])
+    }
+
+    return env
+}
+
+func unwrapReturnValue(obj object.Object) object.Object {
+    if returnValue, ok := obj.(*object.ReturnValue); ok {
+        return returnValue.Value
+    }
+
+    return obj
+}
+
+// Everything except false and null is truthy
+func isTruthy(obj object.Object) bool {
+    switch obj {
+    case NULL:
+        return false
+    case TRUE:
+        return true
+    case FALSE:
+        return false
+    default:
+        return true
+    }
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+    return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func isError(obj object.Object) bool {
+    if obj != nil {
+        return obj.Type() == object.ERROR_OBJ
+    }
+    return false
+}
@@ -0,0 +1,1020 @@
+package evaluator
+
+import (
+	"monkeylang/lexer"
+	"monkeylang/object"
+	"monkeylang/parser"
+	"testing"
+)
+
+func testEval(t *testing.T, input string) object.Object {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 0 {
+        t.Fatalf("parser errors for %q: %v", input, p.Errors())
+    }
+
+    env := object.NewEnvironment()
+    return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+    result, ok := obj.(*object.Integer)
+    if !ok {
+        t.Errorf("object is not Integer got %T (%+v)", obj, obj)
+        return false
+    }
+
+    if result.Value != expected {
+        t.Errorf("object has wrong value got %d want %d", result.Value, expected)
+        return false
+    }
+
+    return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+    result, ok := obj.(*object.Boolean)
+    if !ok {
+        t.Errorf("object is not Boolean got %T (%+v)", obj, obj)
+        return false
+    }
+
+    if result.Value != expected {
+        t.Errorf("object has wrong value got %t want %t", result.Value, expected)
+        return false
+    }
+
+    return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+    if obj != NULL {
+        t.Errorf("object is not NULL got %T (%+v)", obj, obj)
+        return false
+    }
+    return true
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"5", 5},
+        {"10", 10},
+        {"-5", -5},
+        {"-10", -10},
+        {"5 + 5 + 5 + 5 - 10", 10},
+        {"2 * 2 * 2 * 2 * 2", 32},
+        {"-50 + 100 + -50", 0},
+        {"5 * 2 + 10", 20},
+        {"5 + 2 * 10", 25},
+        {"20 + 2 * -10", 0},
+        {"50 / 2 * 2 + 10", 60},
+        {"2 * (5 + 10)", 30},
+        {"3 * 3 * 3 + 10", 37},
+        {"3 * (3 * 3) + 10", 37},
+        {"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        testIntegerObject(t, evaluated, tt.expected)
+    }
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    } {
+        {"true", true},
+        {"false", false},
+        {"1 < 2", true},
+        {"1 > 2", false},
+        {"1 < 1", false},
+        {"1 == 1", true},
+        {"1 != 1", false},
+        {"1 == 2", false},
+        {"1 != 2", true},
+        {"true == true", true},
+        {"false == false", true},
+        {"true == false", false},
+        {"true != false", true},
+        {"(1 < 2) == true", true},
+        {"(1 > 2) == true", false},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        testBooleanObject(t, evaluated, tt.expected)
+    }
+}
+
+func TestBangOperator(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    } {
+        {"!true", false},
+        {"!false", true},
+        {"!5", false},
+        {"!!true", true},
+        {"!!false", false},
+        {"!!5", true},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        testBooleanObject(t, evaluated, tt.expected)
+    }
+}
+
+func TestIfElseExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {"if (true) { 10 }", 10},
+        {"if (false) { 10 }", nil},
+        {"if (1) { 10 }", 10},
+        {"if (1 < 2) { 10 }", 10},
+        {"if (1 > 2) { 10 }", nil},
+        {"if (1 > 2) { 10 } else { 20 }", 20},
+        {"if (1 < 2) { 10 } else { 20 }", 10},
+        {"if (true) { }", nil},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        integer, ok := tt.expected.(int)
+        if ok {
+            testIntegerObject(t, evaluated, int64(integer))
+        } else {
+            testNullObject(t, evaluated)
+        }
+    }
+}
+
+func TestReturnStatements(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"return 10;", 10},
+        {"return 10; 9;", 10},
+        {"return 2 * 5; 9;", 10},
+        {"9; return 2 * 5; 9;", 10},
+        {`
+        if (10 > 1) {
+            if (10 > 1) {
+                return 10;
+            }
+
+            return 1;
+        }
+        `, 10},
+        {`
+        let f = fn(x) {
+            return x;
+            x + 10;
+        };
+        f(10);
+        `, 10},
+        {`
+        let f = fn(x) {
+            let result = x + 10;
+            return result;
+            return 10;
+        };
+        f(10);
+        `, 20},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        testIntegerObject(t, evaluated, tt.expected)
+    }
+}
+
+func TestErrorHandling(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMessage string
+    } {
+        {"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+        {"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+        {"-true", "unknown operator: -BOOLEAN"},
+        {"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+        {"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+        {"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+        {`
+        if (10 > 1) {
+            if (10 > 1) {
+                return true + false;
+            }
+
+            return 1;
+        }
+        `, "unknown operator: BOOLEAN + BOOLEAN"},
+        {"foobar", "identifier not found: foobar"},
+        {"10 / (5 - 5)", "division by zero"},
+        {"5(1)", "not a function: INTEGER"},
+        {"fn(x) { x }(1, 2)", "wrong number of arguments: want=1, got=2"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("no error object returned got %T(%+v)", evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expectedMessage {
+            t.Errorf("wrong error message expected %q got %q", tt.expectedMessage, errObj.Message)
+        }
+    }
+}
+
+func TestLetStatements(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let a = 5; a;", 5},
+        {"let a = 5 * 5; a;", 25},
+        {"let a = 5; let b = a; b;", 5},
+        {"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestFunctionObject(t *testing.T) {
+    input := "fn(x) { x + 2; };"
+
+    evaluated := testEval(t, input)
+    fn, ok := evaluated.(*object.Function)
+    if !ok {
+        t.Fatalf("object is not Function got %T (%+v)", evaluated, evaluated)
+    }
+
+    if len(fn.Parameters) != 1 {
+        t.Fatalf("function has wrong parameters got %+v", fn.Parameters)
+    }
+
+    if fn.Parameters[0].String() != "x" {
+        t.Fatalf("parameter is not 'x' got %q", fn.Parameters[0])
+    }
+
+    expectedBody := "{ (x + 2) }"
+    if fn.Body.String() != expectedBody {
+        t.Fatalf("body is not %q got %q", expectedBody, fn.Body.String())
+    }
+}
+
+func TestFunctionApplication(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let identity = fn(x) { x; }; identity(5);", 5},
+        {"let identity = fn(x) { return x; }; identity(5);", 5},
+        {"let double = fn(x) { x * 2; }; double(5);", 10},
+        {"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+        {"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+        {"fn(x) { x; }(5)", 5},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestNestedEnvironments(t *testing.T) {
+    input := `
+    let x = 10;
+    let f = fn(y) {
+        let x = y * 2;
+        x;
+    };
+    f(3) + x;
+    `
+
+    testIntegerObject(t, testEval(t, input), 16)
+}
+
+func TestClosures(t *testing.T) {
+    input := `
+    let newAdder = fn(x) {
+        fn(y) { x + y };
+    };
+
+    let addTwo = newAdder(2);
+    addTwo(2);
+    `
+
+    testIntegerObject(t, testEval(t, input), 4)
+}
+
+func TestClosuresCaptureByReference(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        // Each counter has its own n, kept between calls
+        {`
+        let newCounter = fn() {
+            let n = 0;
+            fn() { n += 1 }
+        };
+        let a = newCounter();
+        let b = newCounter();
+        a(); a(); b();
+        a() * 10 + b()
+        `, 32},
+        // Closures made by one call share its bindings
+        {`
+        let newCell = fn(n) {
+            {"get": fn() { n }, "set": fn(v) { n = v }}
+        };
+        let cell = newCell(1);
+        cell["set"](5);
+        cell["get"]()
+        `, 5},
+        // A closure sees bindings changed after it was made
+        {"let x = 1; let f = fn() { x }; x = 7; f()", 7},
+        {"let x = 1; let bump = fn() { x += 1 }; bump(); bump(); x", 3},
+        // Parameters and lets shadow, so the outer x is untouched
+        {"let x = 1; let f = fn(x) { x = x * 10; x }; f(5) + x", 51},
+        {"let x = 1; let f = fn() { let x = 2; x = 3; x }; f() + x", 4},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestHigherOrderFunctions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let add = fn(a) { fn(b) { fn(c) { a + b + c } } }; add(1)(20)(300)", 321},
+        {"let add = fn(a) { fn(b) { a + b } }; let inc = add(1); inc(inc(5))", 7},
+        {`
+        let curry = fn(f) { fn(a) { fn(b) { f(a, b) } } };
+        let mul = curry(fn(a, b) { a * b });
+        mul(6)(7)
+        `, 42},
+        {`
+        let compose = fn(f, g) { fn(x) { f(g(x)) } };
+        let double = fn(x) { x * 2 };
+        let square = fn(x) { x * x };
+        compose(double, square)(3) + compose(square, double)(3)
+        `, 54},
+        {`
+        let sumOf = fn(xs, f) {
+            let total = 0;
+            for (x in xs) { total += f(x) }
+            total
+        };
+        sumOf([1, 2, 3], fn(x) { x * x })
+        `, 14},
+        {"let twice = fn(f) { fn(x) { f(f(x)) } }; twice(twice(fn(x) { x + 3 }))(0)", 12},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestRecursion(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(10)", 3628800},
+        {"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(15)", 610},
+        // Recursive lets inside a function body
+        {`
+        let outer = fn(n) {
+            let down = fn(i) { if (i == 0) { 0 } else { 1 + down(i - 1) } };
+            down(n)
+        };
+        outer(50)
+        `, 50},
+        // A closure recursing over a captured binding
+        {`
+        let countdown = fn(n) {
+            let steps = 0;
+            let loop = fn(i) { if (i > 0) { steps += 1; loop(i - 1) } };
+            loop(n);
+            steps
+        };
+        countdown(25)
+        `, 25},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestMutualRecursion(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    } {
+        {`
+        let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+        let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+        isEven(10)
+        `, true},
+        {`
+        let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+        let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+        isOdd(7) && !isEven(7)
+        `, true},
+        {`
+        let parity = fn(n) {
+            let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+            let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+            even(n)
+        };
+        parity(9)
+        `, false},
+    }
+
+    for _, tt := range tests {
+        testBooleanObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestFunctionErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"let add = fn(a, b) { a + b }; add(1)", "wrong number of arguments to add: want=2, got=1"},
+        {"let f = fn() { 1 }; f(1, 2, 3)", "wrong number of arguments to f: want=0, got=3"},
+        {"let add = fn(a) { fn(b) { a + b } }; add(1)()", "wrong number of arguments: want=1, got=0"},
+        {"let f = fn(a) { a }; let g = f; g()", "wrong number of arguments to f: want=1, got=0"},
+        {"let f = fn(a) { a }; let g = fn() { f() + 1 }; g()", "wrong number of arguments to f: want=1, got=0"},
+        {"let f = fn(a) { a }; f(1, missing)", "identifier not found: missing"},
+        {"let f = fn() { y }; let y = 1; let g = fn() { let z = 2; z }; g(); z", "identifier not found: z"},
+        {"let x = 1; x(2)", "not a function: INTEGER"},
+        {"let f = fn() { 5 }; f()()", "not a function: INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("%q no error object returned got %T(%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("%q wrong error message expected %q got %q", tt.input, tt.expected, errObj.Message)
+        }
+    }
+}
+
+func TestStringLiteral(t *testing.T) {
+    evaluated := testEval(t, `"Hello World!"`)
+
+    str, ok := evaluated.(*object.String)
+    if !ok {
+        t.Fatalf("object is not String got %T (%+v)", evaluated, evaluated)
+    }
+
+    if str.Value != "Hello World!" {
+        t.Errorf("String has wrong value got %q", str.Value)
+    }
+}
+
+func TestStringConcatenation(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {`"Hello" + " " + "World!"`, "Hello World!"},
+        {`"a" == "a"`, true},
+        {`"a" != "a"`, false},
+        {`"a" - "b"`, "unknown operator: STRING - STRING"},
+        {`"a" + 1`, "type mismatch: STRING + INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        switch expected := tt.expected.(type) {
+        case bool:
+            testBooleanObject(t, evaluated, expected)
+        case string:
+            switch obj := evaluated.(type) {
+            case *object.String:
+                if obj.Value != expected {
+                    t.Errorf("String has wrong value got %q want %q", obj.Value, expected)
+                }
+            case *object.Error:
+                if obj.Message != expected {
+                    t.Errorf("wrong error message got %q want %q", obj.Message, expected)
+                }
+            default:
+                t.Errorf("unexpected object %T (%+v)", evaluated, evaluated)
+            }
+        }
+    }
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {"1.5", 1.5},
+        {"-2.5", -2.5},
+        {"1.5 + 1.5", 3.0},
+        {"1 + 0.5", 1.5},
+        {"3 / 2.0", 1.5},
+        {"2.0 * 3", 6.0},
+        {"0x10 + 0b1", int64(17)},
+        {"1_000 - 1", int64(999)},
+        {"1.5 < 2", true},
+        {"2.0 == 2", true},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        switch expected := tt.expected.(type) {
+        case float64:
+            f, ok := evaluated.(*object.Float)
+            if !ok {
+                t.Errorf("%q object is not Float got %T (%+v)", tt.input, evaluated, evaluated)
+                continue
+            }
+            if f.Value != expected {
+                t.Errorf("%q Float has wrong value got %g want %g", tt.input, f.Value, expected)
+            }
+        case int64:
+            testIntegerObject(t, evaluated, expected)
+        case bool:
+            testBooleanObject(t, evaluated, expected)
+        }
+    }
+}
+
+func TestFloatInspect(t *testing.T) {
+    tests := []struct {
+        value float64
+        expected string
+    } {
+        {1.5, "1.5"},
+        {2, "2.0"},
+        {-3, "-3.0"},
+        {1e21, "1e+21"},
+    }
+
+    for _, tt := range tests {
+        f := &object.Float{Value: tt.value}
+        if f.Inspect() != tt.expected {
+            t.Errorf("Inspect() expected %q got %q", tt.expected, f.Inspect())
+        }
+    }
+}
+
+func TestInspectQuotesNestedStrings(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {`"a"`, `a`},
+        {`["a", 1, "1"]`, `["a", 1, "1"]`},
+        {`{"a": "b", 1: "1"}`, `{"a": "b", 1: "1"}`},
+        {`[{"k": ["v\n"]}]`, `[{"k": ["v\n"]}]`},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        if evaluated.Inspect() != tt.expected {
+            t.Errorf("%s: Inspect() expected %q got %q", tt.input, tt.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestArrayLiterals(t *testing.T) {
+    evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+
+    result, ok := evaluated.(*object.Array)
+    if !ok {
+        t.Fatalf("object is not Array got %T (%+v)", evaluated, evaluated)
+    }
+
+    if len(result.Elements) != 3 {
+        t.Fatalf("array has wrong number of elements got %d", len(result.Elements))
+    }
+
+    testIntegerObject(t, result.Elements[0], 1)
+    testIntegerObject(t, result.Elements[1], 4)
+    testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {"[1, 2, 3][0]", 1},
+        {"[1, 2, 3][1]", 2},
+        {"[1, 2, 3][2]", 3},
+        {"let i = 0; [1][i];", 1},
+        {"[1, 2, 3][1 + 1];", 3},
+        {"let myArray = [1, 2, 3]; myArray[2];", 3},
+        {"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+        {"[1, 2, 3][3]", nil},
+        {"[1, 2, 3][-1]", 3},
+        {"[1, 2, 3][-3]", 1},
+        {"[1, 2, 3][-4]", nil},
+        {"let fns = [fn(x) { x * 2 }]; fns[0](21)", 42},
+        {"[[1, 2], [3, 4]][1][0]", 3},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        integer, ok := tt.expected.(int)
+        if ok {
+            testIntegerObject(t, evaluated, int64(integer))
+        } else {
+            testNullObject(t, evaluated)
+        }
+    }
+}
+
+func TestSliceExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"[1, 2, 3, 4][1:3]", "[2, 3]"},
+        {"[1, 2, 3, 4][:2]", "[1, 2]"},
+        {"[1, 2, 3, 4][2:]", "[3, 4]"},
+        {"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+        {"[1, 2, 3, 4][-2:]", "[3, 4]"},
+        {"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+        {"[1, 2, 3, 4][3:1]", "[]"},
+        {"[1, 2, 3, 4][-10:10]", "[1, 2, 3, 4]"},
+        {`"héllo"[1:3]`, "él"},
+        {`"héllo"[-1]`, "o"},
+        {"[1, 2][true:]", "ERROR: slice bound must be INTEGER, got BOOLEAN"},
+        {"5[1:]", "ERROR: slice operator not supported: INTEGER"},
+        {`[1]["a"]`, "ERROR: index operator not supported: ARRAY[STRING]"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        if evaluated == nil || evaluated.Inspect() != tt.expected {
+            t.Errorf("%q expected %q got %v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func TestHashLiterals(t *testing.T) {
+    input := `let two = "two";
+    {
+        "one": 10 - 9,
+        two: 1 + 1,
+        "thr" + "ee": 6 / 2,
+        4: 4,
+        true: 5,
+        false: 6
+    }`
+
+    evaluated := testEval(t, input)
+    result, ok := evaluated.(*object.Hash)
+    if !ok {
+        t.Fatalf("Eval didn't return Hash got %T (%+v)", evaluated, evaluated)
+    }
+
+    expected := map[object.HashKey]int64 {
+        (&object.String{Value: "one"}).HashKey(): 1,
+        (&object.String{Value: "two"}).HashKey(): 2,
+        (&object.String{Value: "three"}).HashKey(): 3,
+        (&object.Integer{Value: 4}).HashKey(): 4,
+        TRUE.HashKey(): 5,
+        FALSE.HashKey(): 6,
+    }
+
+    if len(result.Pairs) != len(expected) {
+        t.Fatalf("Hash has wrong num of pairs got %d", len(result.Pairs))
+    }
+
+    for expectedKey, expectedValue := range expected {
+        pair, ok := result.Pairs[expectedKey]
+        if !ok {
+            t.Errorf("no pair for given key in Pairs")
+        }
+
+        testIntegerObject(t, pair.Value, expectedValue)
+    }
+
+    expectedInspect := `{"one": 1, "two": 2, "three": 3, 4: 4, true: 5, false: 6}`
+    if result.Inspect() != expectedInspect {
+        t.Errorf("Inspect() expected %q got %q", expectedInspect, result.Inspect())
+    }
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {`{"foo": 5}["foo"]`, 5},
+        {`{"foo": 5}["bar"]`, nil},
+        {`let key = "foo"; {"foo": 5}[key]`, 5},
+        {`{}["foo"]`, nil},
+        {`{5: 5}[5]`, 5},
+        {`{true: 5}[true]`, 5},
+        {`{false: 5}[false]`, 5},
+        {`{1.5: 5}[1.5]`, 5},
+        {`let key = fn() { "k" }; {key(): 7}["k"]`, 7},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+        integer, ok := tt.expected.(int)
+        if ok {
+            testIntegerObject(t, evaluated, int64(integer))
+        } else {
+            testNullObject(t, evaluated)
+        }
+    }
+}
+
+func TestUnusableHashKeys(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+        {`{[1]: 2}`, "unusable as hash key: ARRAY"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("no error object returned got %T(%+v)", evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("wrong error message expected %q got %q", tt.expected, errObj.Message)
+        }
+    }
+}
+
+func TestExtendedOperators(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {"7 % 3", int64(1)},
+        {"-7 % 3", int64(-1)},
+        {"2 ** 10", int64(1024)},
+        {"2 ** 3 ** 2", int64(512)},
+        {"-2 ** 2", int64(-4)},
+        {"2 ** 0", int64(1)},
+        {"2 ** -1", 0.5},
+        {"2.0 ** 0.5 * 2.0 ** 0.5", 2.0000000000000004},
+        {"7.5 % 2", 1.5},
+        {"6 & 3", int64(2)},
+        {"6 | 3", int64(7)},
+        {"6 ^ 3", int64(5)},
+        {"~5", int64(-6)},
+        {"1 << 4", int64(16)},
+        {"-16 >> 2", int64(-4)},
+        {"1 | 2 ^ 3 & 4", int64(3)},
+        {"1 <= 1", true},
+        {"2 <= 1", false},
+        {"1 >= 2", false},
+        {"1.5 >= 1", true},
+        {`"a" < "b"`, true},
+        {`"b" <= "a"`, false},
+        {"true && true", true},
+        {"true && false", false},
+        {"false || true", true},
+        {"1 && 0", true},
+        {"false || false", false},
+        {"1 < 2 && 2 < 3 || false", true},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int64:
+            testIntegerObject(t, evaluated, expected)
+        case bool:
+            testBooleanObject(t, evaluated, expected)
+        case float64:
+            f, ok := evaluated.(*object.Float)
+            if !ok || f.Value != expected {
+                t.Errorf("%q expected %g got %v", tt.input, expected, evaluated)
+            }
+        }
+    }
+}
+
+func TestShortCircuit(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    } {
+        // The right side would fail, so it must not be evaluated
+        {"false && missing", false},
+        {"true || missing", true},
+        {"false && 1 / 0", false},
+        {"let f = fn() { true || undefinedFn() }; f()", true},
+    }
+
+    for _, tt := range tests {
+        testBooleanObject(t, testEval(t, tt.input), tt.expected)
+    }
+
+    evaluated := testEval(t, "true && missing")
+    if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "identifier not found: missing" {
+        t.Errorf("expected the right side to be evaluated got %v", evaluated)
+    }
+}
+
+func TestOperatorErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"5 % 0", "division by zero"},
+        {"1 << -1", "negative shift count: -1"},
+        {"~true", "unknown operator: ~BOOLEAN"},
+        {"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+        {`"a" % "b"`, "unknown operator: STRING % STRING"},
+        {"true <= false", "unknown operator: BOOLEAN <= BOOLEAN"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("%q no error object returned got %T(%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("wrong error message expected %q got %q", tt.expected, errObj.Message)
+        }
+    }
+}
+
+func TestAssignExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let x = 1; x = 2; x", 2},
+        {"let x = 1; x = 5", 5},
+        {"let a = 1; let b = 2; a = b = 3; a + b", 6},
+        {"let x = 10; x += 5; x", 15},
+        {"let x = 10; x -= 3 * 2; x", 4},
+        {"let x = 3; x *= x; x", 9},
+        {"let x = 7; x /= 2; x", 3},
+        {"let x = 7; x %= 4; x", 3},
+        {"let i = 0; let f = fn() { i += 1 }; f(); f(); i", 2},
+        {"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+        {"let a = [1, 2, 3]; a[0] = 10; a[0] + a[1]", 12},
+        {"let a = [1, 2, 3]; a[-1] += 4; a[2]", 7},
+        {`let h = {"k": 1}; h["k"] += 1; h["k"]`, 2},
+        {`let h = {}; h["new"] = 5; h["new"]`, 5},
+        {"let m = [[1, 2], [3, 4]]; m[1][0] = 9; m[1][0]", 9},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+
+    evaluated := testEval(t, `let s = "a"; s += "b"; s`)
+    str, ok := evaluated.(*object.String)
+    if !ok || str.Value != "ab" {
+        t.Errorf("expected \"ab\" got %v", evaluated)
+    }
+}
+
+func TestAssignErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"x = 1", "identifier not found: x"},
+        {"x += 1", "identifier not found: x"},
+        {"let x = true; x += 1", "type mismatch: BOOLEAN + INTEGER"},
+        {"let a = [1]; a[1] = 2", "index out of range: 1 with length 1"},
+        {`let a = [1]; a["0"] = 2`, "index operator not supported: ARRAY[STRING]"},
+        {"let h = {}; h[fn(x) { x }] = 1", "unusable as hash key: FUNCTION"},
+        {`let h = {}; h["k"] += 1`, "type mismatch: NULL + INTEGER"},
+        {`let s = "ab"; s[0] = "c"`, "index assignment not supported: STRING"},
+        {"let x = 1; x = missing", "identifier not found: missing"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("%q no error object returned got %T(%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("%q wrong error message expected %q got %q", tt.input, tt.expected, errObj.Message)
+        }
+    }
+}
+
+func TestLoops(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    } {
+        {"let i = 0; while (i < 10) { i += 1 }; i", 10},
+        {"let i = 0; while (false) { i += 1 }; i", 0},
+        {"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+        {"let sum = 0; for (i, x in [10, 20]) { sum += i * x }; sum", 20},
+        {`let s = ""; for (k in {"a": 1, "b": 2}) { s += k }; if (s == "ab") { 1 } else { 0 }`, 1},
+        {`let sum = 0; for (k, v in {"a": 1, "b": 2}) { sum += v }; sum`, 3},
+        {`let n = 0; for (c in "héllo") { n += 1 }; n`, 5},
+        {"let i = 0; while (true) { i += 1; if (i == 5) { break } }; i", 5},
+        {"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue } sum += x }; sum", 4},
+        {`let n = 0;
+          outer: for (x in [1, 2, 3]) {
+              for (y in [1, 2, 3]) {
+                  if (y == 2) { continue outer }
+                  if (x == 3) { break outer }
+                  n += 1
+              }
+          }
+          n`, 2},
+        {"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x } } }; f()", 2},
+        {"let n = 0; let a = [1, 2]; for (x in a) { a[1] = 5; n += x }; n", 3},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(t, tt.input), tt.expected)
+    }
+}
+
+func TestForCapturesEachPass(t *testing.T) {
+    input := `
+    let fs = [fn() { 0 }, fn() { 0 }];
+    for (i, x in [10, 20]) {
+        fs[i] = fn() { x };
+    }
+    fs[0]() + fs[1]()
+    `
+    testIntegerObject(t, testEval(t, input), 30)
+}
+
+func TestLoopErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"for (x in 5) { }", "cannot iterate over INTEGER"},
+        {"while (missing) { }", "identifier not found: missing"},
+        {"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+        {"let i = 0; while (true) { i += 1; if (i > 2) { i + true } }", "type mismatch: INTEGER + BOOLEAN"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(t, tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("%q no error object returned got %T(%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("%q wrong error message expected %q got %q", tt.input, tt.expected, errObj.Message)
+        }
+    }
+}
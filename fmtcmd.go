@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"monkeylang/format"
+	"os"
+)
+
+// Formats the named files, or stdin when there are none or one is named -,
+// and returns the exit status: 1 if any of them could not be read, parsed
+// or written, or with -d if any of them is not formatted
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result back to the source file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source, and exit with status 1 if there is one")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey fmt [-w] [-d] [files...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	for _, filename := range files {
+		if *write && filename == "-" {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			status = 2
+			continue
+		}
+
+		if fmtFile(filename, *write, *diff) != 0 && status == 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+// Returns 1 on errors, and with diff when the file is not formatted
+func fmtFile(filename string, write, diff bool) int {
+	name, text, ok := readSource(filename)
+	if !ok {
+		return 1
+	}
+	src := []byte(text)
+
+	res, err := format.Source(name, src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if bytes.Equal(src, res) {
+		if !write && !diff {
+			os.Stdout.Write(res)
+		}
+		return 0
+	}
+
+	status := 0
+	if diff {
+		fmt.Print(unifiedDiff(name, string(src), string(res)))
+		status = 1
+	}
+
+	if write {
+		info, err := os.Stat(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := os.WriteFile(filename, res, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else if !diff {
+		os.Stdout.Write(res)
+	}
+	return status
+}
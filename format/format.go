@@ -0,0 +1,552 @@
+// Package format prints Monkey programs in their canonical layout.
+//
+// Blocks are indented by four spaces with one statement per line, binary
+// operators are surrounded by spaces and only the parentheses the parser
+// needs are kept. Comments stay where they were relative to the statements
+// around them, and at most one blank line is kept between statements.
+// Formatting formatted code does not change it.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"monkeylang/ast"
+	"monkeylang/lexer"
+	"monkeylang/object"
+	"monkeylang/parser"
+	"monkeylang/token"
+	"strconv"
+	"strings"
+)
+
+const indentation = "    "
+
+// Source formats a whole program. If src does not parse the parse errors
+// are returned, the filename is only used in their positions.
+func Source(filename string, src []byte) ([]byte, error) {
+    p := parser.New(lexer.NewFile(filename, string(src)))
+    program := p.ParseProgram()
+
+    if parseErrors := p.ParseErrors(); len(parseErrors) > 0 {
+        errs := []error{}
+        for _, err := range parseErrors {
+            errs = append(errs, err)
+        }
+        return nil, errors.Join(errs...)
+    }
+
+    pr := &printer{src: src, comments: program.Comments}
+    pr.program(program)
+
+    return pr.out.Bytes(), nil
+}
+
+// Node formats a program, statement or expression without the source it
+// was parsed from. The comments of a program are kept, string literals are
+// written with canonical escapes.
+func Node(w io.Writer, node ast.Node) error {
+    pr := &printer{}
+
+    switch n := node.(type) {
+    case *ast.Program:
+        pr.comments = n.Comments
+        pr.program(n)
+    case ast.Statement:
+        pr.statement(n)
+    case ast.Expression:
+        pr.expr(n)
+    }
+
+    _, err := w.Write(pr.out.Bytes())
+    return err
+}
+
+type printer struct {
+    out bytes.Buffer
+    src []byte // The source, if the tree was parsed from it
+    depth int
+
+    comments []token.Comment
+    next int // The first comment not printed yet
+    lastLine int // Source line of the last thing printed, 0 at the start of a block
+}
+
+// Everything that is left once all statements are printed
+var endOfFile = token.Position{Offset: math.MaxInt, Line: math.MaxInt}
+
+func (p *printer) print(s ...string) {
+    for _, str := range s {
+        p.out.WriteString(str)
+    }
+}
+
+func (p *printer) indent() {
+    p.print(strings.Repeat(indentation, p.depth))
+}
+
+// Keeps a single blank line where the source had one or more
+func (p *printer) blankLine(line int) {
+    if p.lastLine > 0 && line > p.lastLine+1 {
+        p.print("\n")
+    }
+}
+
+// Prints the comments before pos, each on a line of its own
+func (p *printer) leadingComments(pos token.Position) {
+    if !pos.IsValid() {
+        return
+    }
+
+    for p.before(pos) {
+        c := p.comments[p.next]
+        p.next += 1
+
+        p.blankLine(c.Pos.Line)
+        p.indent()
+        p.print(c.Text, "\n")
+
+        // A comment left behind inside the previous statement is printed
+        // after it, which must not open a gap before the next one
+        p.lastLine = max(p.lastLine, c.End.Line)
+    }
+}
+
+// Prints the comments that start on the line something ended on, up to
+// where the next thing starts
+func (p *printer) trailingComments(end, limit token.Position) {
+    if !end.IsValid() {
+        return
+    }
+
+    for p.next < len(p.comments) && p.comments[p.next].Pos.Line == end.Line && p.before(limit) {
+        p.print(" ", p.comments[p.next].Text)
+        p.next += 1
+    }
+    p.lastLine = end.Line
+}
+
+// Whether the next comment to print comes before pos
+func (p *printer) before(pos token.Position) bool {
+    return pos.IsValid() && p.next < len(p.comments) && p.comments[p.next].Pos.Offset < pos.Offset
+}
+
+func (p *printer) program(program *ast.Program) {
+    p.statements(program.Statements, endOfFile, false)
+    p.leadingComments(endOfFile)
+}
+
+// Prints the statements of a program or block that ends at end
+func (p *printer) statements(list []ast.Statement, end token.Position, inBlock bool) {
+    for i, stmt := range list {
+        p.leadingComments(stmt.Pos())
+        p.blankLine(stmt.Pos().Line)
+
+        p.indent()
+        p.statement(stmt)
+
+        var next ast.Statement
+        limit := end
+        if i+1 < len(list) {
+            next = list[i+1]
+            limit = next.Pos()
+        }
+        if needsSemicolon(stmt, next, inBlock) {
+            p.print(";")
+        }
+
+        p.trailingComments(stmt.End(), limit)
+        p.print("\n")
+    }
+}
+
+// Let, return, break and continue always end in a semicolon. Expression
+// statements do too, except for the value at the end of a block, and after
+// an if or a function literal unless the next statement would continue it.
+func needsSemicolon(stmt, next ast.Statement, inBlock bool) bool {
+    switch stmt := stmt.(type) {
+    case *ast.WhileStatement, *ast.ForStatement:
+        return false
+
+    case *ast.ExpressionStatement:
+        if next == nil {
+            return !inBlock && !endsWithBlock(stmt.Expression)
+        }
+
+        if endsWithBlock(stmt.Expression) {
+            nextExp, ok := next.(*ast.ExpressionStatement)
+            return ok && parser.Precedence(firstToken(nextExp.Expression)) > parser.LOWEST
+        }
+    }
+
+    return true
+}
+
+func endsWithBlock(exp ast.Expression) bool {
+    switch exp.(type) {
+    case *ast.IfExpression, *ast.FunctionLiteral:
+        return true
+    }
+    return false
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+    switch s := stmt.(type) {
+    case *ast.LetStatement:
+        p.print("let ", s.Name.Value, " = ")
+        p.expr(s.Value)
+
+    case *ast.ReturnStatement:
+        p.print("return")
+        if s.ReturnValue != nil {
+            p.print(" ")
+            p.expr(s.ReturnValue)
+        }
+
+    case *ast.ExpressionStatement:
+        p.expr(s.Expression)
+
+    case *ast.WhileStatement:
+        p.label(s.Label)
+        p.print("while (")
+        p.expr(s.Condition)
+        p.print(") ")
+        p.block(s.Body)
+
+    case *ast.ForStatement:
+        p.label(s.Label)
+        p.print("for (")
+        if s.Key != nil {
+            p.print(s.Key.Value, ", ")
+        }
+        p.print(s.Value.Value, " in ")
+        p.expr(s.Iterable)
+        p.print(") ")
+        p.block(s.Body)
+
+    case *ast.BreakStatement:
+        p.print("break")
+        if s.Label != nil {
+            p.print(" ", s.Label.Value)
+        }
+
+    case *ast.ContinueStatement:
+        p.print("continue")
+        if s.Label != nil {
+            p.print(" ", s.Label.Value)
+        }
+
+    case *ast.BlockStatement:
+        p.block(s)
+    }
+}
+
+func (p *printer) label(label *ast.Identifier) {
+    if label != nil {
+        p.print(label.Value, ": ")
+    }
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+    if len(block.Statements) == 0 && !p.before(block.RBrace.Pos) {
+        p.print("{}")
+        return
+    }
+
+    p.print("{\n")
+    p.depth += 1
+    p.lastLine = 0
+
+    p.statements(block.Statements, block.RBrace.Pos, true)
+    p.leadingComments(block.RBrace.Pos)
+
+    p.depth -= 1
+    p.indent()
+    p.print("}")
+    p.lastLine = block.RBrace.Pos.Line
+}
+
+// The precedence an expression is parsed at. Literals and other operands
+// bind tighter than any operator.
+func precedence(exp ast.Expression) int {
+    switch exp := exp.(type) {
+    case *ast.AssignExpression:
+        return parser.ASSIGN
+    case *ast.InfixExpression:
+        return parser.Precedence(token.TokenType(exp.Operator))
+    case *ast.PrefixExpression:
+        return parser.PREFIX
+    case *ast.CallExpression, *ast.IndexExpression, *ast.SliceExpression:
+        return parser.INDEX
+    }
+    return parser.INDEX + 1
+}
+
+// An operand needs parentheses if it binds looser than its operator, or as
+// tight but on the side the operator does not group towards
+func operandNeedsParens(operator string, operand ast.Expression, left bool) bool {
+    prec := parser.Precedence(token.TokenType(operator))
+    if precedence(operand) != prec {
+        return precedence(operand) < prec
+    }
+    return parser.RightAssociative(token.TokenType(operator)) == left
+}
+
+// Calls, index and slice expressions all apply to the operand on their
+// left, and to each other without parentheses
+func postfixNeedsParens(operand ast.Expression) bool {
+    return precedence(operand) < parser.CALL
+}
+
+// The type of the first token exp is printed with
+func firstToken(exp ast.Expression) token.TokenType {
+    switch exp := exp.(type) {
+    case *ast.InfixExpression:
+        if operandNeedsParens(exp.Operator, exp.Left, true) {
+            return token.LParen
+        }
+        return firstToken(exp.Left)
+    case *ast.AssignExpression:
+        return firstToken(exp.Target)
+    case *ast.CallExpression:
+        return postfixFirstToken(exp.Function)
+    case *ast.IndexExpression:
+        return postfixFirstToken(exp.Left)
+    case *ast.SliceExpression:
+        return postfixFirstToken(exp.Left)
+    case *ast.PrefixExpression:
+        return token.TokenType(exp.Operator)
+    case *ast.ArrayLiteral:
+        return token.LBracket
+    case *ast.HashLiteral:
+        return token.LBrace
+    }
+    return token.Ident
+}
+
+func postfixFirstToken(operand ast.Expression) token.TokenType {
+    if postfixNeedsParens(operand) {
+        return token.LParen
+    }
+    return firstToken(operand)
+}
+
+func (p *printer) operand(exp ast.Expression, parens bool) {
+    if parens {
+        p.print("(")
+        p.expr(exp)
+        p.print(")")
+    } else {
+        p.expr(exp)
+    }
+}
+
+func (p *printer) expr(exp ast.Expression) {
+    switch e := exp.(type) {
+    case *ast.Identifier:
+        p.print(e.Value)
+
+    case *ast.IntegerLiteral:
+        p.print(literal(e.Token, strconv.FormatInt(e.Value, 10)))
+
+    case *ast.FloatLiteral:
+        p.print(literal(e.Token, object.FormatFloat(e.Value)))
+
+    case *ast.StringLiteral:
+        p.print(p.stringLiteral(e))
+
+    case *ast.Boolean:
+        p.print(strconv.FormatBool(e.Value))
+
+    case *ast.PrefixExpression:
+        p.print(e.Operator)
+        p.operand(e.Right, precedence(e.Right) < parser.PREFIX)
+
+    case *ast.InfixExpression:
+        p.operand(e.Left, operandNeedsParens(e.Operator, e.Left, true))
+        p.print(" ", e.Operator, " ")
+        p.operand(e.Right, operandNeedsParens(e.Operator, e.Right, false))
+
+    case *ast.AssignExpression:
+        p.expr(e.Target)
+        p.print(" ", e.Operator, " ")
+        p.operand(e.Value, precedence(e.Value) < parser.ASSIGN)
+
+    case *ast.IfExpression:
+        p.print("if (")
+        p.expr(e.Condition)
+        p.print(") ")
+        p.block(e.Consequence)
+        if e.Alternative != nil {
+            p.print(" else ")
+            p.block(e.Alternative)
+        }
+
+    case *ast.FunctionLiteral:
+        params := []string{}
+        for _, param := range e.Parameters {
+            params = append(params, param.Value)
+        }
+        p.print("fn(", strings.Join(params, ", "), ") ")
+        p.block(e.Body)
+
+    case *ast.CallExpression:
+        p.operand(e.Function, postfixNeedsParens(e.Function))
+        p.expressionList("(", ")", e.Arguments, e.Token, e.RParen)
+
+    case *ast.ArrayLiteral:
+        p.expressionList("[", "]", e.Elements, e.Token, e.RBracket)
+
+    case *ast.IndexExpression:
+        p.operand(e.Left, postfixNeedsParens(e.Left))
+        p.print("[")
+        p.expr(e.Index)
+        p.print("]")
+
+    case *ast.SliceExpression:
+        p.operand(e.Left, postfixNeedsParens(e.Left))
+        p.print("[")
+        if e.Low != nil {
+            p.expr(e.Low)
+        }
+        p.print(":")
+        if e.High != nil {
+            p.expr(e.High)
+        }
+        p.print("]")
+
+    case *ast.HashLiteral:
+        p.hashLiteral(e)
+    }
+}
+
+// Numbers keep the way they were written, e.g. 0xFF or 1_000
+func literal(tok token.Token, fallback string) string {
+    if tok.Literal != "" {
+        return tok.Literal
+    }
+    return fallback
+}
+
+// Strings keep the way they were written when the source is known, the
+// token only holds their value
+func (p *printer) stringLiteral(s *ast.StringLiteral) string {
+    start, end := s.Token.Pos, s.Token.End
+    if p.src != nil && start.IsValid() && end.Offset <= len(p.src) {
+        return string(p.src[start.Offset:end.Offset])
+    }
+    return ast.Quote(s.Value)
+}
+
+// A list written with line breaks between its elements, or between them
+// and the brackets, stays that way, with one element per line and a
+// trailing comma. Breaks inside an element, like those in the body of a
+// fn, do not count: the printer makes those itself, so counting them
+// would break the list on the next run. bounds holds the start and end of
+// every element in turn.
+func multiline(open, close token.Token, bounds []token.Position) bool {
+    prev := open.Pos
+    for i := 0; i <= len(bounds); i += 2 {
+        next := close.Pos
+        if i < len(bounds) {
+            next = bounds[i]
+        }
+
+        if prev.IsValid() && next.IsValid() && next.Line > prev.Line {
+            return true
+        }
+        if i+1 < len(bounds) {
+            prev = bounds[i+1]
+        }
+    }
+    return false
+}
+
+func (p *printer) expressionList(open, close string, list []ast.Expression, openTok, closeTok token.Token) {
+    bounds := []token.Position{}
+    for _, exp := range list {
+        bounds = append(bounds, exp.Pos(), exp.End())
+    }
+
+    if len(list) == 0 || !multiline(openTok, closeTok, bounds) {
+        p.print(open)
+        for i, exp := range list {
+            if i > 0 {
+                p.print(", ")
+            }
+            p.expr(exp)
+        }
+        p.print(close)
+        return
+    }
+
+    p.print(open, "\n")
+    p.depth += 1
+    p.lastLine = 0
+
+    for i, exp := range list {
+        p.leadingComments(exp.Pos())
+        p.indent()
+        p.expr(exp)
+        p.print(",")
+
+        limit := closeTok.Pos
+        if i+1 < len(list) {
+            limit = list[i+1].Pos()
+        }
+        p.trailingComments(exp.End(), limit)
+        p.print("\n")
+    }
+
+    p.leadingComments(closeTok.Pos)
+    p.depth -= 1
+    p.indent()
+    p.print(close)
+}
+
+func (p *printer) hashLiteral(hash *ast.HashLiteral) {
+    bounds := []token.Position{}
+    for _, pair := range hash.Pairs {
+        bounds = append(bounds, pair.Key.Pos(), pair.Value.End())
+    }
+
+    if len(hash.Pairs) == 0 || !multiline(hash.Token, hash.RBrace, bounds) {
+        p.print("{")
+        for i, pair := range hash.Pairs {
+            if i > 0 {
+                p.print(", ")
+            }
+            p.expr(pair.Key)
+            p.print(": ")
+            p.expr(pair.Value)
+        }
+        p.print("}")
+        return
+    }
+
+    p.print("{\n")
+    p.depth += 1
+    p.lastLine = 0
+
+    for i, pair := range hash.Pairs {
+        p.leadingComments(pair.Key.Pos())
+        p.indent()
+        p.expr(pair.Key)
+        p.print(": ")
+        p.expr(pair.Value)
+        p.print(",")
+
+        limit := hash.RBrace.Pos
+        if i+1 < len(hash.Pairs) {
+            limit = hash.Pairs[i+1].Key.Pos()
+        }
+        p.trailingComments(pair.Value.End(), limit)
+        p.print("\n")
+    }
+
+    p.leadingComments(hash.RBrace.Pos)
+    p.depth -= 1
+    p.indent()
+    p.print("}")
+}
@@ -0,0 +1,175 @@
+package format
+
+import (
+	"bytes"
+	"monkeylang/ast"
+	"monkeylang/lexer"
+	"monkeylang/parser"
+	"monkeylang/token"
+	"testing"
+)
+
+var formatTests = []struct {
+    input string
+    expected string
+} {
+    // Spacing and statements
+    {"let x=1+2*3;let y = (1+2)*3", "let x = 1 + 2 * 3;\nlet y = (1 + 2) * 3;\n"},
+    {"return;return  -x", "return;\nreturn -x;\n"},
+    {"f( a,b ,c ) ;g()", "f(a, b, c);\ng();\n"},
+    {`{"a":1,"b":[1,2]}`, "{\"a\": 1, \"b\": [1, 2]};\n"},
+    {"a[ : 2];a[1:];a[i]", "a[:2];\na[1:];\na[i];\n"},
+    {"x+=1;h[k]=v", "x += 1;\nh[k] = v;\n"},
+
+    // Only the parentheses the parser needs
+    {"((a))", "a;\n"},
+    {"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+    {"a ** (b ** c); (a ** b) ** c", "a ** b ** c;\n(a ** b) ** c;\n"},
+    {"-(a ** b); (-a) ** b; a ** (-b)", "-a ** b;\n(-a) ** b;\na ** (-b);\n"},
+    {"(a && b) || c; a && (b || c)", "a && b || c;\na && (b || c);\n"},
+    {"!(a == b); (!a) == b", "!(a == b);\n!a == b;\n"},
+    {"(f(x))[0]; (a + b)[0]; (a[1:2])(3)", "f(x)[0];\n(a + b)[0];\na[1:2](3);\n"},
+    {"x = (y = 1); (x = y) + 1", "x = y = 1;\n(x = y) + 1;\n"},
+    {"1 | (2 ^ (3 & 4)); (1 | 2) ^ 3", "1 | 2 ^ 3 & 4;\n(1 | 2) ^ 3;\n"},
+
+    // Blocks
+    {"let f = fn(a,b){ return a+b; };", "let f = fn(a, b) {\n    return a + b;\n};\n"},
+    {"if(x){1}else{ if (y) {2} }", "if (x) {\n    1\n} else {\n    if (y) {\n        2\n    }\n}\n"},
+    {"let f = fn() {}; fn(){}", "let f = fn() {};\nfn() {}\n"},
+    {"let f = fn() { a; b };", "let f = fn() {\n    a;\n    b\n};\n"},
+    {"outer: for (k,v in h){while(true){break outer}}",
+        "outer: for (k, v in h) {\n    while (true) {\n        break outer;\n    }\n}\n"},
+    {"for (x in xs) { continue; }; x", "for (x in xs) {\n    continue;\n}\nx;\n"},
+
+    // Semicolons after a block only where the next statement would continue it
+    {"if (a) { b }; c", "if (a) {\n    b\n}\nc;\n"},
+    {"if (a) { b }; -c", "if (a) {\n    b\n};\n-c;\n"},
+    {"if (a) { b }; (c + d) * e", "if (a) {\n    b\n};\n(c + d) * e;\n"},
+    {"if (a) { b }; [1]", "if (a) {\n    b\n};\n[1];\n"},
+    {"if (a) { b }; let c = 1", "if (a) {\n    b\n}\nlet c = 1;\n"},
+
+    // Literals keep their spelling
+    {"let s = `raw\\n`; \"a\\tb\"", "let s = `raw\\n`;\n\"a\\tb\";\n"},
+    {"0xFF + 1_000 + 1.5e3", "0xFF + 1_000 + 1.5e3;\n"},
+
+    // Lists written across lines stay that way
+    {"let a = [\n1,\n2 // two\n];", "let a = [\n    1,\n    2, // two\n];\n"},
+    {"let h = {\n\"a\": 1, \"b\": 2};", "let h = {\n    \"a\": 1,\n    \"b\": 2,\n};\n"},
+    {"f(\n  a,\n  /* b */ b)", "f(\n    a,\n    /* b */\n    b,\n);\n"},
+    {"f(g(\n  a), b)", "f(g(\n    a,\n), b);\n"},
+    {"[1 +\n  2, 3]", "[1 + 2, 3];\n"},
+
+    // Blocks inside one-line lists leave the list on one line
+    {"map(xs, fn(x) { x * 2 });", "map(xs, fn(x) {\n    x * 2\n});\n"},
+    {"[fn(x) { x }, 2]", "[fn(x) {\n    x\n}, 2];\n"},
+    {"f(if (a) { 1 } else { 2 })", "f(if (a) {\n    1\n} else {\n    2\n});\n"},
+    {`{"k": fn(x) { x }}`, "{\"k\": fn(x) {\n    x\n}};\n"},
+    {"f(\n  fn() { a },\n  b)", "f(\n    fn() {\n        a\n    },\n    b,\n);\n"},
+
+    // Comments and blank lines
+    {"// header\n\nlet x = 1; // one\n/* two */\nlet y = 2;\n\n\n\nlet z = fn() {\n  // inside\n  x\n  // end\n};\n# tail",
+        "// header\n\nlet x = 1; // one\n/* two */\nlet y = 2;\n\nlet z = fn() {\n    // inside\n    x\n    // end\n};\n# tail\n"},
+    {"let f = fn() {\n\n  a;\n\n  b;\n\n};", "let f = fn() {\n    a;\n\n    b\n};\n"},
+    {"a; b; // c", "a;\nb; // c\n"},
+    {"if (x) { /* nothing */ }", "if (x) {\n    /* nothing */\n}\n"},
+    {"let x = 1 + // one\n  2;\nlet y = 3;", "let x = 1 + 2;\n// one\nlet y = 3;\n"},
+    {"", ""},
+    {"// only a comment", "// only a comment\n"},
+}
+
+func TestSource(t *testing.T) {
+    for _, tt := range formatTests {
+        out, err := Source("", []byte(tt.input))
+        if err != nil {
+            t.Errorf("%q failed: %v", tt.input, err)
+            continue
+        }
+
+        if string(out) != tt.expected {
+            t.Errorf("%q\nexpected\n%s\ngot\n%s", tt.input, tt.expected, out)
+        }
+    }
+}
+
+func parseString(t *testing.T, input string) string {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 0 {
+        t.Fatalf("%q has parse errors %v", input, p.Errors())
+    }
+    return program.String()
+}
+
+func TestFormattingKeepsMeaning(t *testing.T) {
+    for _, tt := range formatTests {
+        out, err := Source("", []byte(tt.input))
+        if err != nil {
+            t.Fatalf("%q failed: %v", tt.input, err)
+        }
+
+        if parseString(t, string(out)) != parseString(t, tt.input) {
+            t.Errorf("%q formatted as %q parses differently", tt.input, out)
+        }
+    }
+}
+
+func TestIdempotence(t *testing.T) {
+    for _, tt := range formatTests {
+        once, err := Source("", []byte(tt.input))
+        if err != nil {
+            t.Fatalf("%q failed: %v", tt.input, err)
+        }
+
+        twice, err := Source("", once)
+        if err != nil {
+            t.Fatalf("%q failed the second time: %v", once, err)
+        }
+
+        if !bytes.Equal(once, twice) {
+            t.Errorf("formatting %q again changed it\nonce\n%s\ntwice\n%s", tt.input, once, twice)
+        }
+    }
+}
+
+func TestSourceErrors(t *testing.T) {
+    _, err := Source("main.mk", []byte("let = 1;\nlet x 2;"))
+    if err == nil {
+        t.Fatalf("expected an error")
+    }
+
+    expected := "main.mk:1:5: Expected IDENT , got = instead\nmain.mk:2:7: Expected = , got INT instead"
+    if err.Error() != expected {
+        t.Errorf("expected %q got %q", expected, err.Error())
+    }
+}
+
+func TestNode(t *testing.T) {
+    ident := func(name string) *ast.Identifier {
+        return &ast.Identifier{Token: token.Token{Type: token.Ident, Literal: name}, Value: name}
+    }
+
+    // Built by hand, without positions or source
+    node := &ast.InfixExpression{
+        Operator: "*",
+        Left: &ast.InfixExpression{Left: ident("a"), Operator: "+", Right: ident("b")},
+        Right: &ast.CallExpression{
+            Function: ident("f"),
+            Arguments: []ast.Expression{
+                &ast.StringLiteral{Value: "x\ny"},
+                &ast.IntegerLiteral{Value: 2},
+                &ast.FloatLiteral{Value: 2},
+                &ast.FloatLiteral{Value: 0.5},
+            },
+        },
+    }
+
+    var out bytes.Buffer
+    if err := Node(&out, node); err != nil {
+        t.Fatalf("Node failed: %v", err)
+    }
+
+    if expected := `(a + b) * f("x\ny", 2, 2.0, 0.5)`; out.String() != expected {
+        t.Errorf("expected %q got %q", expected, out.String())
+    }
+}
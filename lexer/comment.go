@@ -0,0 +1,93 @@
+package lexer
+
+import (
+	"monkeylang/token"
+	"strings"
+)
+
+func (l *Lexer) atComment() bool {
+    return l.ch == '#' || l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+// Skips whitespace and returns the comments in between
+func (l *Lexer) readLeadingComments() []token.Comment {
+    var comments []token.Comment
+
+    l.skipWhiteSpace()
+    for l.atComment() {
+        comments = append(comments, l.readComment())
+        l.skipWhiteSpace()
+    }
+
+    return comments
+}
+
+// Returns the comments after a token that start on its line. The newline
+// itself is left alone.
+func (l *Lexer) readTrailingComments() []token.Comment {
+    var comments []token.Comment
+
+    for {
+        for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+            l.readChar()
+        }
+
+        if !l.atComment() {
+            return comments
+        }
+
+        comment := l.readComment()
+        comments = append(comments, comment)
+        if !comment.IsBlock() {
+            return comments
+        }
+    }
+}
+
+func (l *Lexer) readComment() token.Comment {
+    start := l.position()
+    block := l.ch == '/' && l.peekChar() == '*'
+
+    if block {
+        l.readBlockComment(start)
+    } else {
+        for l.ch != '\n' && l.ch != eof {
+            l.readChar()
+        }
+    }
+
+    end := l.position()
+    text := l.input[start.Offset:end.Offset]
+
+    // Windows line endings are not part of a line comment
+    if !block && strings.HasSuffix(text, "\r") {
+        text = text[:len(text)-1]
+        end = offsetPos(end, -1)
+    }
+
+    return token.Comment{Text: text, Pos: start, End: end}
+}
+
+// Block comments nest, so /* a /* b */ c */ is a single comment
+func (l *Lexer) readBlockComment(start token.Position) {
+    depth := 0
+
+    for {
+        switch {
+        case l.ch == eof:
+            l.incompleteAt(start, "unterminated block comment")
+            return
+        case l.ch == '/' && l.peekChar() == '*':
+            depth += 1
+            l.readChar()
+        case l.ch == '*' && l.peekChar() == '/':
+            depth -= 1
+            l.readChar()
+            if depth == 0 {
+                l.readChar()
+                return
+            }
+        }
+        l.readChar()
+    }
+}
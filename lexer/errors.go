@@ -0,0 +1,27 @@
+package lexer
+
+import "monkeylang/token"
+
+// Error is a problem found while scanning, such as an unterminated string.
+// The lexer still returns a token for the offending text.
+type Error struct {
+    Pos token.Position
+    Msg string
+    Incomplete bool // The input ended inside a token that may span lines
+}
+
+func (e *Error) Error() string {
+    return e.Pos.String() + ": " + e.Msg
+}
+
+func (l *Lexer) Errors() []*Error {
+    return l.errors
+}
+
+func (l *Lexer) errorAt(pos token.Position, msg string) {
+    l.errors = append(l.errors, &Error{Pos: pos, Msg: msg})
+}
+
+func (l *Lexer) incompleteAt(pos token.Position, msg string) {
+    l.errors = append(l.errors, &Error{Pos: pos, Msg: msg, Incomplete: true})
+}
@@ -1,60 +1,143 @@
 package lexer
 
-import "monkeylang/token"
+import (
+	"fmt"
+	"monkeylang/token"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The lexer reads the input as UTF-8, one rune at a time
+const (
+    eof = -1
+    bom = 0xFEFF
+)
 
 type Lexer struct {
 	input   string
 	pos     int 
     readpos int // The Next character position
-	ch      byte
+	ch      rune
+
+    filename  string
+    line      int
+    lineStart int // Offset of the first character of the current line
+
+    errors []*Error
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+    return NewFile("", input)
+}
+
+// Same as New but every token position carries the given filename
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
+
+    // A leading byte order mark is not part of the source
+    if l.ch == bom {
+        l.readChar()
+    }
 	return l
 }
 
 func (l *Lexer) readChar() {
+    if l.ch == '\n' {
+        l.line += 1
+        l.lineStart = l.readpos
+    }
+
+	l.pos = l.readpos
 	if l.readpos >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readpos]
+		l.ch = eof
+		return
 	}
-	l.pos = l.readpos
 
-	l.readpos += 1
+    r, width := utf8.DecodeRuneInString(l.input[l.readpos:])
+    l.ch = r
+	l.readpos += width
+
+    if r == utf8.RuneError && width == 1 {
+        l.errorAt(l.position(), fmt.Sprintf("invalid UTF-8 encoding %#x", l.input[l.pos]))
+    } else if r == bom && l.pos > 0 {
+        l.errorAt(l.position(), "byte order mark is only allowed at the start of the input")
+    }
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
     if l.readpos >= len(l.input) {
-        return 0
-    } else {
-        return l.input[l.readpos]
+        return eof
+    }
+
+    r, _ := utf8.DecodeRuneInString(l.input[l.readpos:])
+    return r
+}
+
+// The position of the current character
+func (l *Lexer) position() token.Position {
+    return token.Position{
+        Filename: l.filename,
+        Offset: l.pos,
+        Line: l.line,
+        Column: l.pos - l.lineStart + 1,
     }
 }
 
 func (l *Lexer) NextToken() token.Token {
+    leading := l.readLeadingComments()
+
+    start := l.position()
+    t := l.scanToken()
+    t.Pos = start
+    t.End = l.position()
+
+    t.Leading = leading
+    if t.Type != token.EOF {
+        t.Trailing = l.readTrailingComments()
+    }
+
+    return t
+}
+
+func (l *Lexer) scanToken() token.Token {
     var t token.Token 
-    l.skipWhiteSpace()
 
     switch l.ch {
     case '=':
         if l.peekChar() == '=' {
-            ch := l.ch
-            l.readChar()
-            t = token.Token{Type: token.EqualTo, Literal: string(ch) + string(l.ch) }
+            t = l.readTwoCharToken(token.EqualTo)
         } else {
             t = newToken(token.Assign, l.ch)
         }
     case '!':
         if l.peekChar() == '=' {
-            ch := l.ch
-            l.readChar()
-            t = token.Token{ Type: token.NotEqualTo, Literal: string(ch) + string(l.ch) }
+            t = l.readTwoCharToken(token.NotEqualTo)
         } else {
             t = newToken(token.Bang, l.ch)
         }
+    case '&':
+        if l.peekChar() == '&' {
+            t = l.readTwoCharToken(token.And)
+        } else {
+            t = newToken(token.Ampersand, l.ch)
+        }
+    case '|':
+        if l.peekChar() == '|' {
+            t = l.readTwoCharToken(token.Or)
+        } else {
+            t = newToken(token.Pipe, l.ch)
+        }
+    case '^':
+        t = newToken(token.Caret, l.ch)
+    case '~':
+        t = newToken(token.Tilde, l.ch)
+    case '%':
+        if l.peekChar() == '=' {
+            t = l.readTwoCharToken(token.PercentAssign)
+        } else {
+            t = newToken(token.Percent, l.ch)
+        }
     case ';':
         t = newToken(token.SemiColon, l.ch)
     case '(':
@@ -65,36 +148,82 @@ func (l *Lexer) NextToken() token.Token {
         t = newToken(token.LBrace, l.ch)
     case '}':
         t = newToken(token.RBrace, l.ch)
+    case '[':
+        t = newToken(token.LBracket, l.ch)
+    case ']':
+        t = newToken(token.RBracket, l.ch)
     case ',':
         t = newToken(token.Comma, l.ch)
     case ':':
         t = newToken(token.Colon, l.ch)
     case '+':
-        t = newToken(token.Plus, l.ch)
+        if l.peekChar() == '=' {
+            t = l.readTwoCharToken(token.PlusAssign)
+        } else {
+            t = newToken(token.Plus, l.ch)
+        }
     case '*':
-        t = newToken(token.Asterisk, l.ch)
+        if l.peekChar() == '*' {
+            t = l.readTwoCharToken(token.Power)
+        } else if l.peekChar() == '=' {
+            t = l.readTwoCharToken(token.AsteriskAssign)
+        } else {
+            t = newToken(token.Asterisk, l.ch)
+        }
     case '/':
-        t = newToken(token.Slash, l.ch)
+        if l.peekChar() == '=' {
+            t = l.readTwoCharToken(token.SlashAssign)
+        } else {
+            t = newToken(token.Slash, l.ch)
+        }
     case '<':
-        t = newToken(token.LT, l.ch)
+        switch l.peekChar() {
+        case '=':
+            t = l.readTwoCharToken(token.LTE)
+        case '<':
+            t = l.readTwoCharToken(token.ShiftLeft)
+        default:
+            t = newToken(token.LT, l.ch)
+        }
     case '>':
-        t = newToken(token.GT, l.ch)
+        switch l.peekChar() {
+        case '=':
+            t = l.readTwoCharToken(token.GTE)
+        case '>':
+            t = l.readTwoCharToken(token.ShiftRight)
+        default:
+            t = newToken(token.GT, l.ch)
+        }
     case '-':
-        t = newToken(token.Minus, l.ch)
-    case 0:
+        if l.peekChar() == '=' {
+            t = l.readTwoCharToken(token.MinusAssign)
+        } else {
+            t = newToken(token.Minus, l.ch)
+        }
+    case '"':
+        return l.readString()
+    case '`':
+        return l.readRawString()
+    case eof:
+        // Stay on the end of the input so EOF keeps a stable position
         t.Literal = ""
         t.Type = token.EOF
+        return t
 
     default: 
         if isLetter(l.ch) {
             t.Literal = l.readIdent()
             t.Type = token.LookupIdent(t.Literal)
             return t } else if isDigit(l.ch) {
-            t.Type = token.Int
-            t.Literal = l.readNumber()
-            return t
+            return l.readNumber()
         } else {
             t = newToken(token.Illegal, l.ch)
+            if l.ch == utf8.RuneError && l.readpos-l.pos == 1 {
+                // Keep the offending byte, readChar already reported it
+                t.Literal = l.input[l.pos:l.readpos]
+            } else {
+                l.errorAt(l.position(), fmt.Sprintf("illegal character %q", l.ch))
+            }
         }
     }
 
@@ -102,16 +231,7 @@ func (l *Lexer) NextToken() token.Token {
     return t
 }
 
-func (l *Lexer) readNumber() string {
-    pos := l.pos
-    for isDigit(l.ch) {
-        l.readChar()
-    }
-
-    return l.input[pos:l.pos]
-}
-
-func  isDigit(ch byte) bool {
+func  isDigit(ch rune) bool {
     return '0' <= ch && ch <= '9'
 }
 
@@ -121,18 +241,32 @@ func (l *Lexer) skipWhiteSpace() {
     }
 }
 
+// Identifiers follow the Go spec: a letter followed by letters and digits,
+// where both may be any Unicode letter or digit
 func (l *Lexer) readIdent() string {
     pos := l.pos
-    for isLetter(l.ch) {
+    for isLetter(l.ch) || isUnicodeDigit(l.ch) {
         l.readChar()
     }
     return l.input[pos:l.pos]
 }
 
-func isLetter(ch byte) bool {
-    return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch  == '_'
+func isLetter(ch rune) bool {
+    return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch  == '_' ||
+    ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+func isUnicodeDigit(ch rune) bool {
+    return isDigit(ch) || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
+}
+
+// Builds a token from the current and the next character
+func (l *Lexer) readTwoCharToken(tt token.TokenType) token.Token {
+    ch := l.ch
+    l.readChar()
+    return token.Token{Type: tt, Literal: string(ch) + string(l.ch)}
 }
 
-func newToken(tt token.TokenType, ch byte) token.Token {
+func newToken(tt token.TokenType, ch rune) token.Token {
     return token.Token{Type: tt, Literal: string(ch)}
 }
@@ -13,7 +13,7 @@ func TestNextToken(t *testing.T) {
     };
 
     let result = add(five, ten);
-    !-/*5;
+    !-/ *5;
     5 < 10 > 5;
 
     if (5 < 10)  {
@@ -122,3 +122,464 @@ func TestNextToken(t *testing.T) {
 
     }
 }
+
+func TestTokenPositions(t *testing.T) {
+    input := "let x = 10;\n  x == y\n"
+
+    tests := []struct {
+        expectedType token.TokenType
+        line int
+        column int
+        offset int
+        endColumn int
+    } {
+        {token.Let, 1, 1, 0, 4},
+        {token.Ident, 1, 5, 4, 6},
+        {token.Assign, 1, 7, 6, 8},
+        {token.Int, 1, 9, 8, 11},
+        {token.SemiColon, 1, 11, 10, 12},
+        {token.Ident, 2, 3, 14, 4},
+        {token.EqualTo, 2, 5, 16, 7},
+        {token.Ident, 2, 8, 19, 9},
+        {token.EOF, 3, 1, 21, 1},
+        {token.EOF, 3, 1, 21, 1},
+    }
+
+    l := NewFile("test.mk", input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("t[%d] token type wrong expected: %q. got: %q", i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Pos.Filename != "test.mk" {
+            t.Errorf("t[%d] filename wrong got %q", i, tok.Pos.Filename)
+        }
+
+        if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column || tok.Pos.Offset != tt.offset {
+            t.Errorf("t[%d] position wrong expected %d:%d (offset %d) got %d:%d (offset %d)",
+            i, tt.line, tt.column, tt.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+        }
+
+        if tok.End.Column != tt.endColumn {
+            t.Errorf("t[%d] end column wrong expected %d got %d", i, tt.endColumn, tok.End.Column)
+        }
+    }
+}
+
+func TestStringLiterals(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedType token.TokenType
+        expectedLiteral string
+    } {
+        {`"foobar"`, token.String, "foobar"},
+        {`"foo bar"`, token.String, "foo bar"},
+        {`""`, token.String, ""},
+        {`"a\nb\tc"`, token.String, "a\nb\tc"},
+        {`"say \"hi\""`, token.String, `say "hi"`},
+        {`"back\\slash"`, token.String, `back\slash`},
+        {`"\x41\x62"`, token.String, "Ab"},
+        {`"\u{e9}\u{1F600}"`, token.String, "é\U0001F600"},
+        {"`raw \\n \"string\"`", token.String, `raw \n "string"`},
+        {"`two\nlines`", token.String, "two\nlines"},
+    }
+
+    for i, tt := range tests {
+        l := New(tt.input)
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] token type wrong expected %q got %q", i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Errorf("tests[%d] literal wrong expected %q got %q", i, tt.expectedLiteral, tok.Literal)
+        }
+
+        if len(l.Errors()) != 0 {
+            t.Errorf("tests[%d] unexpected errors %v", i, l.Errors())
+        }
+
+        if next := l.NextToken(); next.Type != token.EOF {
+            t.Errorf("tests[%d] string did not end the input got %q", i, next.Type)
+        }
+    }
+}
+
+func TestStringErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedType token.TokenType
+        expectedError string
+    } {
+        {`"abc`, token.Illegal, "1:1: unterminated string literal"},
+        {"let s = \"abc\nx", token.Illegal, "1:9: unterminated string literal"},
+        {"`abc", token.Illegal, "1:1: unterminated raw string literal"},
+        {`"a\qb"`, token.String, `1:3: unknown escape sequence \q`},
+        {`"\x4"`, token.String, `1:2: \x escape needs exactly 2 hex digits`},
+        {`"\u{}"`, token.String, `1:2: \u escape must look like \u{1F600}`},
+        {`"\u{D800}"`, token.String, `1:2: \u{D800} is not a valid code point`},
+        {`@`, token.Illegal, `1:1: illegal character '@'`},
+    }
+
+    for i, tt := range tests {
+        l := New(tt.input)
+
+        tok := l.NextToken()
+        for tok.Type == token.Let || tok.Type == token.Ident || tok.Type == token.Assign {
+            tok = l.NextToken()
+        }
+
+        if tok.Type != tt.expectedType {
+            t.Errorf("tests[%d] token type wrong expected %q got %q", i, tt.expectedType, tok.Type)
+        }
+
+        errors := l.Errors()
+        if len(errors) != 1 {
+            t.Fatalf("tests[%d] expected 1 error got %v", i, errors)
+        }
+
+        if errors[0].Error() != tt.expectedError {
+            t.Errorf("tests[%d] error wrong expected %q got %q", i, tt.expectedError, errors[0].Error())
+        }
+    }
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+    input := "let café = \"naïve\"; // é\nlet 名前 = x1 + ñ2_b; π"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    } {
+        {token.Let, "let"},
+        {token.Ident, "café"},
+        {token.Assign, "="},
+        {token.String, "naïve"},
+        {token.SemiColon, ";"},
+        {token.Let, "let"},
+        {token.Ident, "名前"},
+        {token.Assign, "="},
+        {token.Ident, "x1"},
+        {token.Plus, "+"},
+        {token.Ident, "ñ2_b"},
+        {token.SemiColon, ";"},
+        {token.Ident, "π"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("t[%d] token type wrong expected: %q. got: %q", i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("t[%d] literal wrong expected: %q. got: %q", i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+
+    if len(l.Errors()) != 0 {
+        t.Errorf("unexpected errors %v", l.Errors())
+    }
+}
+
+func TestUnicodePositions(t *testing.T) {
+    // Columns count bytes, like go/token
+    l := New("\uFEFFlet é = 1")
+
+    tests := []struct {
+        literal string
+        offset int
+        column int
+    } {
+        {"let", 3, 4},
+        {"é", 7, 8},
+        {"=", 10, 11},
+    }
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Literal != tt.literal {
+            t.Fatalf("t[%d] literal wrong expected %q got %q", i, tt.literal, tok.Literal)
+        }
+
+        if tok.Pos.Offset != tt.offset || tok.Pos.Column != tt.column {
+            t.Errorf("t[%d] position wrong expected offset %d column %d got offset %d column %d",
+            i, tt.offset, tt.column, tok.Pos.Offset, tok.Pos.Column)
+        }
+    }
+}
+
+func TestInvalidUTF8(t *testing.T) {
+    l := New("let a = \xff;\nlet b = \"x\xfey\";")
+
+    var types []token.TokenType
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+        types = append(types, tok.Type)
+    }
+
+    if types[3] != token.Illegal {
+        t.Errorf("invalid byte should lex as ILLEGAL got %q", types[3])
+    }
+
+    expected := []string {
+        "1:9: invalid UTF-8 encoding 0xff",
+        "2:11: invalid UTF-8 encoding 0xfe",
+    }
+
+    errors := l.Errors()
+    if len(errors) != len(expected) {
+        t.Fatalf("expected %d errors got %v", len(expected), errors)
+    }
+
+    for i, msg := range expected {
+        if errors[i].Error() != msg {
+            t.Errorf("errors[%d] expected %q got %q", i, msg, errors[i].Error())
+        }
+    }
+}
+
+func TestNumberLiterals(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedType token.TokenType
+    } {
+        {"0", token.Int},
+        {"1_000_000", token.Int},
+        {"0x1F", token.Int},
+        {"0XdEaD_bEeF", token.Int},
+        {"0o17", token.Int},
+        {"0O_7", token.Int},
+        {"0755", token.Int},
+        {"0b1010", token.Int},
+        {"0B_1_0", token.Int},
+        {"1.5", token.Float},
+        {"0.25", token.Float},
+        {"1e10", token.Float},
+        {"1E+10", token.Float},
+        {"2.5e-3", token.Float},
+        {"1_000.000_1", token.Float},
+        {"0755.5", token.Float},
+    }
+
+    for i, tt := range tests {
+        l := New(tt.input)
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Errorf("tests[%d] %q type wrong expected %q got %q", i, tt.input, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.input {
+            t.Errorf("tests[%d] literal wrong expected %q got %q", i, tt.input, tok.Literal)
+        }
+
+        if len(l.Errors()) != 0 {
+            t.Errorf("tests[%d] %q unexpected errors %v", i, tt.input, l.Errors())
+        }
+
+        if next := l.NextToken(); next.Type != token.EOF {
+            t.Errorf("tests[%d] %q was split, next token %q", i, tt.input, next.Literal)
+        }
+    }
+}
+
+func TestMalformedNumbers(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedError string
+    } {
+        {"0x", "1:1: hexadecimal literal has no digits"},
+        {"0b", "1:1: binary literal has no digits"},
+        {"1e", "1:3: exponent has no digits"},
+        {"1.5e+", "1:6: exponent has no digits"},
+        {"0x1G", "1:4: invalid digit 'G' in hexadecimal literal"},
+        {"0b102", "1:5: invalid digit '2' in binary literal"},
+        {"0o8", "1:3: invalid digit '8' in octal literal"},
+        {"089", "1:2: invalid digit '8' in octal literal"},
+        {"1__000", "1:2: '_' must separate successive digits"},
+        {"1000_", "1:5: '_' must separate successive digits"},
+        {"1_.5", "1:2: '_' must separate successive digits"},
+        {"12abc", "1:3: invalid character 'a' in decimal literal"},
+    }
+
+    for i, tt := range tests {
+        l := New(tt.input)
+        tok := l.NextToken()
+
+        if tok.Literal != tt.input {
+            t.Errorf("tests[%d] literal wrong expected %q got %q", i, tt.input, tok.Literal)
+        }
+
+        errors := l.Errors()
+        if len(errors) != 1 {
+            t.Errorf("tests[%d] %q expected 1 error got %v", i, tt.input, errors)
+            continue
+        }
+
+        if errors[0].Error() != tt.expectedError {
+            t.Errorf("tests[%d] error wrong expected %q got %q", i, tt.expectedError, errors[0].Error())
+        }
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `# shebang style
+// leading
+let x = 1; // trailing
+/* block /* nested */ still comment */ x /* inline */ + 2 /* a */ /* b */
+// before EOF`
+
+    l := New(input)
+
+    tests := []struct {
+        literal string
+        leading []string
+        trailing []string
+    } {
+        {"let", []string{"# shebang style", "// leading"}, nil},
+        {"x", nil, nil},
+        {"=", nil, nil},
+        {"1", nil, nil},
+        {";", nil, []string{"// trailing"}},
+        {"x", []string{"/* block /* nested */ still comment */"}, []string{"/* inline */"}},
+        {"+", nil, nil},
+        {"2", nil, []string{"/* a */", "/* b */"}},
+        {"", []string{"// before EOF"}, nil},
+    }
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Literal != tt.literal {
+            t.Fatalf("t[%d] literal wrong expected %q got %q", i, tt.literal, tok.Literal)
+        }
+
+        if !equalComments(tok.Leading, tt.leading) {
+            t.Errorf("t[%d] leading comments wrong expected %q got %v", i, tt.leading, tok.Leading)
+        }
+
+        if !equalComments(tok.Trailing, tt.trailing) {
+            t.Errorf("t[%d] trailing comments wrong expected %q got %v", i, tt.trailing, tok.Trailing)
+        }
+    }
+
+    if len(l.Errors()) != 0 {
+        t.Errorf("unexpected errors %v", l.Errors())
+    }
+}
+
+func equalComments(comments []token.Comment, texts []string) bool {
+    if len(comments) != len(texts) {
+        return false
+    }
+
+    for i, c := range comments {
+        if c.Text != texts[i] {
+            return false
+        }
+    }
+
+    return true
+}
+
+func TestCommentPositions(t *testing.T) {
+    l := New("a // one\r\n/* two\nlines */ b")
+
+    a := l.NextToken()
+    b := l.NextToken()
+
+    if len(a.Trailing) != 1 || a.Trailing[0].Text != "// one" {
+        t.Fatalf("a.Trailing wrong got %v", a.Trailing)
+    }
+
+    if a.Trailing[0].Pos.String() != "1:3" || a.Trailing[0].End.String() != "1:9" {
+        t.Errorf("line comment span wrong got %s-%s", a.Trailing[0].Pos, a.Trailing[0].End)
+    }
+
+    if len(b.Leading) != 1 {
+        t.Fatalf("b.Leading wrong got %v", b.Leading)
+    }
+
+    if b.Leading[0].Pos.String() != "2:1" || b.Leading[0].End.String() != "3:9" {
+        t.Errorf("block comment span wrong got %s-%s", b.Leading[0].Pos, b.Leading[0].End)
+    }
+
+    if b.Pos.String() != "3:10" {
+        t.Errorf("b.Pos wrong got %s", b.Pos)
+    }
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+    l := New("x /* a /* b */ c")
+
+    l.NextToken()
+    if tok := l.NextToken(); tok.Type != token.EOF {
+        t.Errorf("expected EOF got %q", tok.Type)
+    }
+
+    errors := l.Errors()
+    if len(errors) != 1 || errors[0].Error() != "1:3: unterminated block comment" {
+        t.Errorf("errors wrong got %v", errors)
+    }
+}
+
+func TestBrackets(t *testing.T) {
+    l := New("a[1:-2]")
+
+    expected := []token.TokenType{token.Ident, token.LBracket, token.Int, token.Colon, token.Minus, token.Int, token.RBracket, token.EOF}
+    for i, tt := range expected {
+        tok := l.NextToken()
+        if tok.Type != tt {
+            t.Fatalf("t[%d] token type wrong expected %q got %q", i, tt, tok.Type)
+        }
+    }
+}
+
+func TestOperators(t *testing.T) {
+    input := "<= >= && || % ** & | ^ ~ << >> < > * ! = += -= *= /= %="
+
+    expected := []token.TokenType {
+        token.LTE, token.GTE, token.And, token.Or, token.Percent, token.Power,
+        token.Ampersand, token.Pipe, token.Caret, token.Tilde, token.ShiftLeft,
+        token.ShiftRight, token.LT, token.GT, token.Asterisk, token.Bang,
+        token.Assign, token.PlusAssign, token.MinusAssign, token.AsteriskAssign,
+        token.SlashAssign, token.PercentAssign, token.EOF,
+    }
+
+    l := New(input)
+    for i, tt := range expected {
+        tok := l.NextToken()
+        if tok.Type != tt {
+            t.Fatalf("t[%d] token type wrong expected %q got %q", i, tt, tok.Type)
+        }
+        if tt != token.EOF && tok.Literal != string(tt) {
+            t.Errorf("t[%d] literal wrong expected %q got %q", i, tt, tok.Literal)
+        }
+    }
+}
+
+func TestLoopKeywords(t *testing.T) {
+    input := "while for in break continue inside"
+
+    expected := []token.TokenType {
+        token.While, token.For, token.In, token.Break, token.Continue,
+        token.Ident, token.EOF,
+    }
+
+    l := New(input)
+    for i, tt := range expected {
+        tok := l.NextToken()
+        if tok.Type != tt {
+            t.Fatalf("t[%d] token type wrong expected %q got %q", i, tt, tok.Type)
+        }
+    }
+}
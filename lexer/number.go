@@ -0,0 +1,143 @@
+package lexer
+
+import (
+	"fmt"
+	"monkeylang/token"
+)
+
+var baseNames = map[int]string {
+    2: "binary",
+    8: "octal",
+    10: "decimal",
+    16: "hexadecimal",
+}
+
+// Reads an integer or float literal following the Go syntax: 0x, 0o and 0b
+// prefixes, a leading 0 for legacy octal, `_` between digits, and decimal
+// floats with an optional exponent. The literal is the raw source text.
+// Malformed literals are reported and still returned as a single token.
+func (l *Lexer) readNumber() token.Token {
+    start := l.position()
+    tt := token.TokenType(token.Int)
+    base := 10
+
+    if l.ch == '0' {
+        switch l.peekChar() {
+        case 'x', 'X':
+            base = 16
+        case 'o', 'O':
+            base = 8
+        case 'b', 'B':
+            base = 2
+        }
+    }
+
+    if base != 10 {
+        l.readChar()
+        l.readChar()
+        l.readAlnum()
+    } else {
+        l.readDecimalDigits()
+
+        if l.ch == '.' && isDigit(l.peekChar()) {
+            tt = token.Float
+            l.readChar()
+            l.readDecimalDigits()
+        }
+
+        if l.ch == 'e' || l.ch == 'E' {
+            tt = token.Float
+            l.readChar()
+            if l.ch == '+' || l.ch == '-' {
+                l.readChar()
+            }
+
+            exp := l.pos
+            l.readDecimalDigits()
+            if l.pos == exp {
+                l.errorAt(l.position(), "exponent has no digits")
+                return token.Token{Type: tt, Literal: l.input[start.Offset:l.pos]}
+            }
+        }
+
+        // Letters glued to a number, as in 12abc
+        if isLetter(l.ch) {
+            pos := l.position()
+            l.readAlnum()
+            l.errorAt(pos, fmt.Sprintf("invalid character %q in %s literal", l.input[pos.Offset], baseNames[base]))
+            return token.Token{Type: tt, Literal: l.input[start.Offset:l.pos]}
+        }
+    }
+
+    lit := l.input[start.Offset:l.pos]
+    l.checkNumber(start, lit, base, tt)
+
+    return token.Token{Type: tt, Literal: lit}
+}
+
+func (l *Lexer) readDecimalDigits() {
+    for isDigit(l.ch) || l.ch == '_' {
+        l.readChar()
+    }
+}
+
+func (l *Lexer) readAlnum() {
+    for isDigit(l.ch) || l.ch == '_' || 'a' <= l.ch && l.ch <= 'z' || 'A' <= l.ch && l.ch <= 'Z' {
+        l.readChar()
+    }
+}
+
+// Reports the first problem with the digits and separators of lit
+func (l *Lexer) checkNumber(start token.Position, lit string, base int, tt token.TokenType) {
+    digits := 0
+    prefix := 0
+    if base != 10 {
+        prefix = 2
+    } else if tt == token.Int && len(lit) > 1 && lit[0] == '0' {
+        // 0755 is an octal integer, but 0755.5 is a decimal float
+        base = 8
+    }
+
+    isBaseDigit := func(ch byte) bool {
+        switch base {
+        case 16:
+            return isHexDigit(rune(ch))
+        default:
+            return '0' <= ch && ch <= '9' && int(ch-'0') < base
+        }
+    }
+
+    for i := prefix; i < len(lit); i++ {
+        ch := lit[i]
+
+        switch {
+        case ch == '_':
+            prevOK := i == prefix && prefix > 0 || i > 0 && isBaseDigit(lit[i-1])
+            nextOK := i+1 < len(lit) && isBaseDigit(lit[i+1])
+            if !prevOK || !nextOK {
+                l.errorAt(offsetPos(start, i), "'_' must separate successive digits")
+                return
+            }
+        case tt == token.Float && (ch == '.' || ch == 'e' || ch == 'E' || ch == '+' || ch == '-'):
+            // Already validated by readNumber
+        case isBaseDigit(ch):
+            digits += 1
+        case tt == token.Float && isDigit(rune(ch)):
+            digits += 1
+        default:
+            l.errorAt(offsetPos(start, i), fmt.Sprintf("invalid digit %q in %s literal", ch, baseNames[base]))
+            return
+        }
+    }
+
+    if digits == 0 {
+        l.errorAt(start, fmt.Sprintf("%s literal has no digits", baseNames[base]))
+    }
+}
+
+// The position i bytes into a token that does not span lines
+func offsetPos(start token.Position, i int) token.Position {
+    start.Offset += i
+    start.Column += i
+    return start
+}
@@ -0,0 +1,132 @@
+package lexer
+
+import (
+	"fmt"
+	"monkeylang/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Reads a double quoted string and decodes its escape sequences. The
+// literal of the token is the decoded value without the quotes.
+func (l *Lexer) readString() token.Token {
+    start := l.position()
+    var out strings.Builder
+
+    l.readChar()
+    for l.ch != '"' {
+        if l.ch == eof || l.ch == '\n' {
+            l.errorAt(start, "unterminated string literal")
+            return token.Token{Type: token.Illegal, Literal: l.input[start.Offset:l.pos]}
+        }
+
+        if l.ch == '\\' {
+            l.readEscape(&out)
+            continue
+        }
+
+        out.WriteRune(l.ch)
+        l.readChar()
+    }
+    l.readChar()
+
+    return token.Token{Type: token.String, Literal: out.String()}
+}
+
+// Reads a backtick string. Nothing is escaped and it may span lines.
+func (l *Lexer) readRawString() token.Token {
+    start := l.position()
+
+    l.readChar()
+    for l.ch != '`' {
+        if l.ch == eof {
+            l.incompleteAt(start, "unterminated raw string literal")
+            return token.Token{Type: token.Illegal, Literal: l.input[start.Offset:l.pos]}
+        }
+        l.readChar()
+    }
+
+    lit := l.input[start.Offset+1:l.pos]
+    l.readChar()
+
+    return token.Token{Type: token.String, Literal: lit}
+}
+
+// Decodes the escape sequence starting at the current backslash into out,
+// leaving the lexer on the first character after it
+func (l *Lexer) readEscape(out *strings.Builder) {
+    pos := l.position()
+    l.readChar()
+
+    switch l.ch {
+    case 'n':
+        out.WriteByte('\n')
+    case 't':
+        out.WriteByte('\t')
+    case 'r':
+        out.WriteByte('\r')
+    case '0':
+        out.WriteByte(0)
+    case '"', '\\':
+        out.WriteRune(l.ch)
+    case 'x':
+        l.readChar()
+        hex := l.readHexDigits(2)
+        if len(hex) != 2 {
+            l.errorAt(pos, "\\x escape needs exactly 2 hex digits")
+            return
+        }
+        value, _ := strconv.ParseUint(hex, 16, 8)
+        out.WriteByte(byte(value))
+        return
+    case 'u':
+        l.readUnicodeEscape(pos, out)
+        return
+    case eof, '\n':
+        // Leave the end of the line to readString, which reports it
+        return
+    default:
+        l.errorAt(pos, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
+    }
+
+    l.readChar()
+}
+
+// Reads the `{...}` part of a \u{...} escape holding 1 to 6 hex digits
+func (l *Lexer) readUnicodeEscape(pos token.Position, out *strings.Builder) {
+    l.readChar()
+    if l.ch != '{' {
+        l.errorAt(pos, "\\u escape must look like \\u{1F600}")
+        return
+    }
+
+    l.readChar()
+    hex := l.readHexDigits(6)
+    if l.ch != '}' || len(hex) == 0 {
+        l.errorAt(pos, "\\u escape must look like \\u{1F600}")
+        return
+    }
+    l.readChar()
+
+    value, _ := strconv.ParseUint(hex, 16, 32)
+    if value > utf8.MaxRune || (0xD800 <= value && value <= 0xDFFF) {
+        l.errorAt(pos, fmt.Sprintf("\\u{%s} is not a valid code point", hex))
+        return
+    }
+
+    out.WriteRune(rune(value))
+}
+
+// Reads up to max hex digits
+func (l *Lexer) readHexDigits(max int) string {
+    pos := l.pos
+    for l.pos-pos < max && isHexDigit(l.ch) {
+        l.readChar()
+    }
+    return l.input[pos:l.pos]
+}
+
+func isHexDigit(ch rune) bool {
+    return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
@@ -1,18 +1,272 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"monkeylang/ast"
+	"monkeylang/ast/astdump"
+	"monkeylang/ast/astjson"
+	"monkeylang/evaluator"
+	"monkeylang/lexer"
+	"monkeylang/object"
+	"monkeylang/parser"
 	"monkeylang/repl"
+	"monkeylang/token"
 	"os"
 	"os/user"
 )
 
+const usage = `usage: monkey <command> [arguments]
+
+commands:
+  run file.mk [args...]                  evaluate a program, args are bound to args
+  tokens file.mk                         print the tokens of a program
+  ast [--format=json|sexpr|dot] file.mk  print the syntax tree of a program
+  check file.mk...                       report syntax errors without running
+  fmt [-w] [-d] [files...]               format programs
+  repl                                   start an interactive console
+
+A file named - is read from standard input.
+`
+
+// Every command takes its arguments and returns the exit status
+var commands = map[string]func(args []string) int{
+	"run":    runCommand,
+	"tokens": tokensCommand,
+	"ast":    astCommand,
+	"check":  checkCommand,
+	"fmt":    fmtCommand,
+	"repl":   replCommand,
+}
+
 func main() {
-	user, err := user.Current()
+	if len(os.Args) < 2 {
+		os.Exit(replCommand(nil))
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		fmt.Print(usage)
+		return
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "monkey: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	os.Exit(command(os.Args[2:]))
+}
+
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey run file.mk [args...]")
+		return 2
+	}
+
+	program, ok := parseFile(args[0])
+	if !ok {
+		return 1
+	}
+
+	scriptArgs := &object.Array{}
+	for _, arg := range args[1:] {
+		scriptArgs.Elements = append(scriptArgs.Elements, &object.String{Value: arg})
+	}
+
+	env := object.NewEnvironment()
+	env.Set("args", scriptArgs)
+
+	result := evaluator.Eval(program, env)
+	if result == nil {
+		return 0
+	}
+
+	switch result.Type() {
+	case object.ERROR_OBJ:
+		fmt.Fprintln(os.Stderr, result.Inspect())
+		return 1
+	case object.NULL_OBJ:
+		return 0
+	}
+
+	fmt.Println(result.Inspect())
+	return 0
+}
+
+// Prints one token per line: position, type and literal, followed by the
+// lexer's errors on stderr. Returns 1 if there were any. The positions
+// leave out the filename, which is the same on every line.
+func tokensCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey tokens file.mk")
+		return 2
+	}
+
+	filename, src, ok := readSource(args[0])
+	if !ok {
+		return 1
+	}
+
+	l := lexer.NewFile(filename, src)
+	for {
+		tok := l.NextToken()
+		pos := tok.Pos
+		pos.Filename = ""
+		fmt.Printf("%-10s %-10s %q\n", pos, tok.Type, tok.Literal)
+
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	for _, err := range l.Errors() {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(l.Errors()) != 0 {
+		return 1
+	}
+	return 0
+}
+
+func astCommand(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	format := flags.String("format", "sexpr", "output `format`: json, sexpr or dot")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey ast [--format=json|sexpr|dot] file.mk")
+		flags.PrintDefaults()
+	}
+
+	files, err := parseInterspersed(flags, args)
 	if err != nil {
-		panic(err)
+		return 2
+	}
+	if len(files) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var dump func(w io.Writer, node ast.Node) error
+	switch *format {
+	case "json":
+		dump = func(w io.Writer, node ast.Node) error {
+			data, err := astjson.MarshalIndent(node, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(w, string(data))
+			return err
+		}
+	case "sexpr":
+		dump = astdump.FprintSExpr
+	case "dot":
+		dump = astdump.FprintDOT
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q, want json, sexpr or dot\n", *format)
+		return 2
+	}
+
+	program, ok := parseFile(files[0])
+	if !ok {
+		return 1
+	}
+
+	if err := dump(os.Stdout, program); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Parses every file and reports all their errors, 1 if there were any
+func checkCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey check file.mk...")
+		return 2
+	}
+
+	status := 0
+	for _, filename := range args {
+		if _, ok := parseFile(filename); !ok {
+			status = 1
+		}
+	}
+	return status
+}
+
+func replCommand(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey repl")
+		return 2
+	}
+
+	// Minimal containers often have no passwd entry for the current user
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
 	}
 
-	fmt.Printf("Hello %s! Welcome the the Monkey Console\n", user.Username)
+	if name != "" {
+		fmt.Printf("Hello %s! Welcome the the Monkey Console\n", name)
+	} else {
+		fmt.Println("Hello! Welcome the the Monkey Console")
+	}
 	repl.Start(os.Stdin, os.Stdout)
+	return 0
+}
+
+// Reads the named file, or stdin for -. Errors are reported to stderr
+func readSource(filename string) (string, string, bool) {
+	var src []byte
+	var err error
+
+	if filename == "-" {
+		filename = "<stdin>"
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(filename)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return filename, "", false
+	}
+	return filename, string(src), true
+}
+
+// Reads and parses the named file, reporting any errors to stderr
+func parseFile(filename string) (*ast.Program, bool) {
+	filename, src, ok := readSource(filename)
+	if !ok {
+		return nil, false
+	}
+
+	p := parser.New(lexer.NewFile(filename, src))
+	program := p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		for _, e := range errors {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		return nil, false
+	}
+	return program, true
+}
+
+// Parses flags wherever they appear among the arguments, so that both
+// `ast --format=dot file.mk` and `ast file.mk --format=dot` work. Returns
+// the arguments that are not flags
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	rest := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return rest, nil
+		}
+
+		rest = append(rest, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Runs command with stdout going to a file, and returns what it printed
+// along with the exit status
+func captureStdout(t *testing.T, command func(args []string) int, args ...string) (string, int) {
+	t.Helper()
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	status := command(args)
+	os.Stdout = stdout
+
+	printed, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(printed), status
+}
+
+func TestFmtDiff(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "nl.mk")
+	if err := os.WriteFile(filename, []byte("let x = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, status := captureStdout(t, fmtCommand, "-d", filename)
+	expected := "--- " + filename + ".orig\n+++ " + filename + "\n" +
+		"@@ -1 +1 @@\n-let x = 1;\n\\ No newline at end of file\n+let x = 1;\n"
+	if output != expected || status != 1 {
+		t.Errorf("expected %q with status 1 got %q with status %d", expected, output, status)
+	}
+
+	if _, status := captureStdout(t, fmtCommand, "-w", filename); status != 0 {
+		t.Fatalf("fmt -w failed with status %d", status)
+	}
+	if output, status := captureStdout(t, fmtCommand, "-d", filename); output != "" || status != 0 {
+		t.Errorf("expected no diff after -w, got %q with status %d", output, status)
+	}
+}
+
+func TestTokensLeaveOutFilename(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "il.mk")
+	if err := os.WriteFile(filename, []byte("let x = 10;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, status := captureStdout(t, tokensCommand, filename)
+	expected := "1:1        Let        \"let\"\n" +
+		"1:5        IDENT      \"x\"\n" +
+		"1:7        =          \"=\"\n" +
+		"1:9        INT        \"10\"\n" +
+		"1:11       ;          \";\"\n" +
+		"1:12       EOF        \"\"\n"
+	if output != expected || status != 0 {
+		t.Errorf("expected %q with status 0 got %q with status %d", expected, output, status)
+	}
+}
@@ -0,0 +1,64 @@
+package object
+
+import "sort"
+
+// Environment maps names to values. Enclosed environments fall back to
+// their outer one, which is how function bodies see enclosing bindings.
+type Environment struct {
+    store map[string]Object
+    outer *Environment
+}
+
+func NewEnvironment() *Environment {
+    return &Environment{store: make(map[string]Object)}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+    env := NewEnvironment()
+    env.outer = outer
+    return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+    obj, ok := e.store[name]
+    if !ok && e.outer != nil {
+        obj, ok = e.outer.Get(name)
+    }
+    return obj, ok
+}
+
+// Binds name in this environment, shadowing any outer binding
+func (e *Environment) Set(name string, val Object) Object {
+    e.store[name] = val
+    return val
+}
+
+// Rebinds name in the innermost environment that already has it, so that
+// every closure sharing that environment sees the new value. Returns false
+// if name is not bound anywhere.
+func (e *Environment) Assign(name string, val Object) bool {
+    for env := e; env != nil; env = env.outer {
+        if _, ok := env.store[name]; ok {
+            env.store[name] = val
+            return true
+        }
+    }
+    return false
+}
+
+// Returns every name visible from this environment, sorted
+func (e *Environment) Names() []string {
+    seen := map[string]bool{}
+    for env := e; env != nil; env = env.outer {
+        for name := range env.store {
+            seen[name] = true
+        }
+    }
+
+    names := make([]string, 0, len(seen))
+    for name := range seen {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,236 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"monkeylang/ast"
+	"strconv"
+	"strings"
+)
+
+type ObjectType string
+
+const (
+    INTEGER_OBJ = "INTEGER"
+    FLOAT_OBJ = "FLOAT"
+    BOOLEAN_OBJ = "BOOLEAN"
+    STRING_OBJ = "STRING"
+    NULL_OBJ = "NULL"
+    RETURN_VALUE_OBJ = "RETURN_VALUE"
+    BREAK_OBJ = "BREAK"
+    CONTINUE_OBJ = "CONTINUE"
+    ERROR_OBJ = "ERROR"
+    FUNCTION_OBJ = "FUNCTION"
+    ARRAY_OBJ = "ARRAY"
+    HASH_OBJ = "HASH"
+)
+
+type Object interface {
+    Type() ObjectType
+    Inspect() string
+}
+
+type Integer struct {
+    Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
+
+type Float struct {
+    Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+func (f *Float) Inspect() string { return FormatFloat(f.Value) }
+
+// FormatFloat writes f the way Monkey source would. Integral values keep a
+// trailing .0 so they do not read as integers.
+func FormatFloat(f float64) string {
+    s := strconv.FormatFloat(f, 'g', -1, 64)
+    if !strings.ContainsAny(s, ".eEnN") {
+        s += ".0"
+    }
+    return s
+}
+
+type Boolean struct {
+    Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
+
+type String struct {
+    Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string { return s.Value }
+
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string { return "null" }
+
+// Wraps the value of a return statement while it bubbles up to the
+// enclosing function call
+type ReturnValue struct {
+    Value Object
+}
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
+
+// Break and Continue bubble up like return values until they reach the loop
+// they belong to. An empty label means the innermost loop.
+type Break struct {
+    Label string
+}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string { return "break" }
+
+type Continue struct {
+    Label string
+}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string { return "continue" }
+
+type Error struct {
+    Message string
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+
+// A function value closes over the environment it was defined in
+type Function struct {
+    Parameters []*ast.Identifier
+    Body *ast.BlockStatement
+    Env *Environment
+    Name string // The let binding it was defined by, for error messages
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+    var out bytes.Buffer
+
+    params := []string{}
+    for _, p := range f.Parameters {
+        params = append(params, p.String())
+    }
+
+    out.WriteString("fn(")
+    out.WriteString(strings.Join(params, ", "))
+    out.WriteString(") ")
+    out.WriteString(f.Body.String())
+
+    return out.String()
+}
+
+type Array struct {
+    Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+    var out bytes.Buffer
+
+    elements := []string{}
+    for _, e := range a.Elements {
+        elements = append(elements, inspectElement(e))
+    }
+
+    out.WriteString("[")
+    out.WriteString(strings.Join(elements, ", "))
+    out.WriteString("]")
+
+    return out.String()
+}
+
+type HashKey struct {
+    Type ObjectType
+    Value uint64
+}
+
+// Hashable is implemented by the objects that can be used as hash keys
+type Hashable interface {
+    HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+    return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (f *Float) HashKey() HashKey {
+    return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+    var value uint64
+    if b.Value {
+        value = 1
+    }
+    return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+    h := fnv.New64a()
+    h.Write([]byte(s.Value))
+    return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+    Key Object
+    Value Object
+}
+
+// Keys remembers the insertion order so that Inspect is stable
+type Hash struct {
+    Pairs map[HashKey]HashPair
+    Keys []HashKey
+}
+
+func NewHash() *Hash {
+    return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Adds or replaces the pair for key
+func (h *Hash) Set(key Hashable, value Object) {
+    hashKey := key.HashKey()
+    if _, ok := h.Pairs[hashKey]; !ok {
+        h.Keys = append(h.Keys, hashKey)
+    }
+    h.Pairs[hashKey] = HashPair{Key: key.(Object), Value: value}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+    var out bytes.Buffer
+
+    pairs := []string{}
+    for _, key := range h.Keys {
+        pair := h.Pairs[key]
+        pairs = append(pairs, fmt.Sprintf("%s: %s", inspectElement(pair.Key), inspectElement(pair.Value)))
+    }
+
+    out.WriteString("{")
+    out.WriteString(strings.Join(pairs, ", "))
+    out.WriteString("}")
+
+    return out.String()
+}
+
+// Strings inside arrays and hashes are quoted, as they are written in
+// Monkey, so that ["1"] and [1] look different
+func inspectElement(obj Object) string {
+    if s, ok := obj.(*String); ok {
+        return ast.Quote(s.Value)
+    }
+    return obj.Inspect()
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"fmt"
+	"monkeylang/token"
+	"strings"
+)
+
+type ErrorCode int
+
+const (
+    _ ErrorCode = iota
+    ErrUnexpectedToken // Found a token other than the expected ones
+    ErrNoPrefixParser // Token cannot start an expression
+    ErrInvalidNumber // Numeric literal out of range or malformed
+    ErrLexical // Reported by the lexer, e.g. an unterminated string
+    ErrDuplicateKey // The same constant key twice in a hash literal
+    ErrInvalidAssignment // Assignment to something that is not a name or index
+    ErrInvalidBranch // break or continue outside a loop or with an unknown label
+    ErrIncomplete // The input ended before the construct around it did
+)
+
+var errorCodeNames = map[ErrorCode]string {
+    ErrUnexpectedToken: "unexpected-token",
+    ErrNoPrefixParser: "no-prefix-parser",
+    ErrInvalidNumber: "invalid-number",
+    ErrLexical: "lexical",
+    ErrDuplicateKey: "duplicate-key",
+    ErrInvalidAssignment: "invalid-assignment",
+    ErrInvalidBranch: "invalid-branch",
+    ErrIncomplete: "incomplete",
+}
+
+func (c ErrorCode) String() string {
+    if name, ok := errorCodeNames[c]; ok {
+        return name
+    }
+    return fmt.Sprintf("error-%d", int(c))
+}
+
+// ParseError is a single diagnostic produced by the parser
+type ParseError struct {
+    Pos token.Position
+    Code ErrorCode
+    Expected []token.TokenType // Empty when any other token would do as well
+    Found token.Token
+    Msg string
+}
+
+func (e *ParseError) Error() string {
+    return e.Pos.String() + ": " + e.Msg
+}
+
+// Renders the expected token set as "a, b or c"
+func expectedString(expected []token.TokenType) string {
+    names := make([]string, len(expected))
+    for i, t := range expected {
+        names[i] = string(t)
+    }
+
+    if len(names) <= 1 {
+        return strings.Join(names, "")
+    }
+    return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
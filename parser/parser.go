@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"monkeylang/ast"
 	"monkeylang/lexer"
@@ -11,23 +12,63 @@ import (
 const (
      _ int = iota 
      LOWEST
+     ASSIGN // = or +=
+     LOGICALOR // ||
+     LOGICALAND // &&
      EQUALS // == 
      LESSGREATER // > or < 
+     BITOR // |
+     BITXOR // ^
+     BITAND // &
+     SHIFT // << or >>
      SUM // + 
      PRODUCT // * 
      PREFIX // -X or !X 
+     POWER // ** binds tighter than a prefix on its left: -2 ** 2 is -(2 ** 2)
      CALL // myFunction(X)
+     INDEX // array[index]
 )
 
 var precedences = map[token.TokenType]int {
+    token.Assign: ASSIGN,
+    token.PlusAssign: ASSIGN,
+    token.MinusAssign: ASSIGN,
+    token.AsteriskAssign: ASSIGN,
+    token.SlashAssign: ASSIGN,
+    token.PercentAssign: ASSIGN,
+    token.Or: LOGICALOR,
+    token.And: LOGICALAND,
     token.EqualTo: EQUALS,
     token.NotEqualTo: EQUALS,
     token.LT: LESSGREATER,
     token.GT: LESSGREATER,
+    token.LTE: LESSGREATER,
+    token.GTE: LESSGREATER,
+    token.Pipe: BITOR,
+    token.Caret: BITXOR,
+    token.Ampersand: BITAND,
+    token.ShiftLeft: SHIFT,
+    token.ShiftRight: SHIFT,
     token.Plus: SUM,
     token.Minus: SUM,
     token.Slash: PRODUCT,
     token.Asterisk: PRODUCT,
+    token.Percent: PRODUCT,
+    token.Power: POWER,
+    token.LParen: CALL,
+    token.LBracket: INDEX,
+}
+
+// Infix operators that group from the right: a ** b ** c is a ** (b ** c)
+// and a = b = c is a = (b = c)
+var rightAssociative = map[token.TokenType]bool {
+    token.Power: true,
+    token.Assign: true,
+    token.PlusAssign: true,
+    token.MinusAssign: true,
+    token.AsteriskAssign: true,
+    token.SlashAssign: true,
+    token.PercentAssign: true,
 }
 
 type (
@@ -39,7 +80,16 @@ type Parser struct {
     l *lexer.Lexer
     curToken token.Token
     peekToken token.Token
-    errors []string
+    errors []*ParseError
+    panicking bool // An error was reported and we have not resynchronized yet
+    braceDepth int // Number of { consumed and not closed yet
+    blockLevel int // braceDepth inside the block being parsed, 0 at the top
+    blockClosed bool // Recovery went past the closing } of the current block
+    lastRBrace token.Token
+    lexErrors int // Lexer errors already attached to a token
+    peekLexErrors []*lexer.Error // Lexer errors found while scanning peekToken
+    comments []token.Comment // Every comment read so far, in source order
+    loops []string // Labels of the enclosing loops, "" for unlabeled ones
     prefixParseFn map[token.TokenType]prefixParseFn
     infixParseFn map[token.TokenType]infixParseFn
 }
@@ -52,34 +102,52 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
     p.infixParseFn[tokenType] = fn
 }
 
-func (p *Parser) peekPrecedence() int {
-    if p, ok := precedences[p.peekToken.Type]; ok {
+// Precedence returns how tightly the infix operator t binds, LOWEST if t
+// cannot continue an expression
+func Precedence(t token.TokenType) int {
+    if p, ok := precedences[t]; ok {
         return p
     }
 
     return LOWEST
 }
 
-func (p *Parser) currentPrecedence() int {
-    if p, ok := precedences[p.curToken.Type]; ok {
-        return p
-    }
+// RightAssociative reports whether a chain of the infix operator t groups
+// from the right
+func RightAssociative(t token.TokenType) bool {
+    return rightAssociative[t]
+}
 
-    return LOWEST
+func (p *Parser) peekPrecedence() int {
+    return Precedence(p.peekToken.Type)
+}
+
+func (p *Parser) currentPrecedence() int {
+    return Precedence(p.curToken.Type)
 }
 
 func New(l *lexer.Lexer) *Parser {
     p := &Parser{
         l: l,
-        errors: []string{},
+        errors: []*ParseError{},
     }
     p.prefixParseFn = make(map[token.TokenType]prefixParseFn)
     p.infixParseFn = make(map[token.TokenType]infixParseFn)
 
     p.registerPrefix(token.Ident, p.parseIdentifier)
     p.registerPrefix(token.Int, p.parseIntegerLiteral)
+    p.registerPrefix(token.Float, p.parseFloatLiteral)
+    p.registerPrefix(token.String, p.parseStringLiteral)
     p.registerPrefix(token.Bang, p.parsePrefixExpression)
     p.registerPrefix(token.Minus, p.parsePrefixExpression)
+    p.registerPrefix(token.Tilde, p.parsePrefixExpression)
+    p.registerPrefix(token.True, p.parseBoolean)
+    p.registerPrefix(token.False, p.parseBoolean)
+    p.registerPrefix(token.LParen, p.parseGroupedExpression)
+    p.registerPrefix(token.If, p.parseIfExpression)
+    p.registerPrefix(token.Function, p.parseFunctionLiteral)
+    p.registerPrefix(token.LBracket, p.parseArrayLiteral)
+    p.registerPrefix(token.LBrace, p.parseHashLiteral)
 
     p.registerInfix(token.Plus, p.parseInfixExpression)
     p.registerInfix(token.Minus, p.parseInfixExpression)
@@ -89,6 +157,20 @@ func New(l *lexer.Lexer) *Parser {
     p.registerInfix(token.NotEqualTo, p.parseInfixExpression)
     p.registerInfix(token.LT, p.parseInfixExpression)
     p.registerInfix(token.GT, p.parseInfixExpression)
+    for _, tt := range []token.TokenType{
+        token.LTE, token.GTE, token.And, token.Or, token.Percent, token.Power,
+        token.Ampersand, token.Pipe, token.Caret, token.ShiftLeft, token.ShiftRight,
+    } {
+        p.registerInfix(tt, p.parseInfixExpression)
+    }
+    p.registerInfix(token.LParen, p.parseCallExpression)
+    for _, tt := range []token.TokenType{
+        token.Assign, token.PlusAssign, token.MinusAssign,
+        token.AsteriskAssign, token.SlashAssign, token.PercentAssign,
+    } {
+        p.registerInfix(tt, p.parseAssignExpression)
+    }
+    p.registerInfix(token.LBracket, p.parseIndexExpression)
 
     // We properly set up the curToken and peekToken fields
     p.nextToken()
@@ -115,30 +197,474 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
         Left: left,
     }
 
+    // Parsing the right side one level lower lets an operator of the same
+    // precedence continue it, which makes the operator group from the right
     precedence := p.currentPrecedence()
+    if rightAssociative[p.curToken.Type] {
+        precedence -= 1
+    }
     p.nextToken()
     expression.Right = p.parseExpression(precedence)
 
     return expression
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+    return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+    return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.True)}
+}
+
+// Parentheses only reset the precedence, they do not get a node of their own
+func (p *Parser) parseGroupedExpression() ast.Expression {
+    p.nextToken()
+    exp := p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RParen) {
+        return nil
+    }
+
+    return exp
+}
+
+// The condition is a plain expression, so the usual parentheses around it
+// are handled by parseGroupedExpression
+func (p *Parser) parseIfExpression() ast.Expression {
+    expression := &ast.IfExpression{Token: p.curToken}
+
+    p.nextToken()
+    expression.Condition = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.LBrace) {
+        return nil
+    }
+    expression.Consequence = p.parseBlockStatement()
+
+    if p.peekTokenIs(token.Else) {
+        p.nextToken()
+
+        if !p.expectPeek(token.LBrace) {
+            return nil
+        }
+        expression.Alternative = p.parseBlockStatement()
+    }
+
+    return expression
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+    // Leave the braces to the recovery of the statement that already failed
+    if p.panicking {
+        return nil
+    }
+
+    block := &ast.BlockStatement{Token: p.curToken}
+    block.Statements = []ast.Statement{}
+
+    outer := p.blockLevel
+    p.blockLevel = p.braceDepth
+    defer func() { p.blockLevel = outer }()
+
+    p.nextToken()
+
+    for !p.curTokenIs(token.RBrace) && !p.curTokenIs(token.EOF) {
+        stmt := p.parseStatementOrRecover()
+        if stmt != nil {
+            block.Statements = append(block.Statements, stmt)
+        }
+
+        if p.blockClosed {
+            p.blockClosed = false
+            block.RBrace = p.lastRBrace
+            return block
+        }
+        p.nextToken()
+    }
+
+    if p.curTokenIs(token.EOF) {
+        p.unexpectedError(p.curToken, token.RBrace)
+    }
+    block.RBrace = p.curToken
+
+    return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+    lit := &ast.FunctionLiteral{Token: p.curToken}
+
+    if !p.expectPeek(token.LParen) {
+        return nil
+    }
+
+    lit.Parameters = p.parseFunctionParameters()
+    if lit.Parameters == nil {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBrace) {
+        return nil
+    }
+
+    // Loops around the function do not reach into its body
+    outer := p.loops
+    p.loops = nil
+    lit.Body = p.parseBlockStatement()
+    p.loops = outer
+
+    return lit
+}
+
+// Parses `a, b, c)`, leaving curToken on the closing paren. Returns nil on
+// error and an empty slice when there are no parameters.
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+    identifiers := []*ast.Identifier{}
+
+    if p.peekTokenIs(token.RParen) {
+        p.nextToken()
+        return identifiers
+    }
+
+    if !p.expectPeek(token.Ident) {
+        return nil
+    }
+    identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+    for p.peekTokenIs(token.Comma) {
+        p.nextToken()
+        if !p.expectPeek(token.Ident) {
+            return nil
+        }
+        identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+    }
+
+    if !p.expectPeek(token.RParen) {
+        return nil
+    }
+
+    return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+    exp := &ast.CallExpression{Token: p.curToken, Function: function}
+    exp.Arguments = p.parseExpressionList(token.RParen)
+    exp.RParen = p.curToken
+
+    return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+    array := &ast.ArrayLiteral{Token: p.curToken}
+    array.Elements = p.parseExpressionList(token.RBracket)
+    array.RBracket = p.curToken
+
+    return array
+}
+
+// A { in expression position always starts a hash literal, blocks are only
+// parsed where a statement list is expected, as after `if` or `fn(...)`.
+// Keys are arbitrary expressions, but the same constant key twice is an
+// error.
+func (p *Parser) parseHashLiteral() ast.Expression {
+    hash := &ast.HashLiteral{Token: p.curToken}
+    hash.Pairs = []ast.HashPair{}
+    seen := map[string]bool{}
+
+    for !p.peekTokenIs(token.RBrace) {
+        p.nextToken()
+        key := p.parseExpression(LOWEST)
+
+        if constant, ok := constantKey(key); ok {
+            if seen[constant] {
+                p.addCheckError(&ParseError{
+                    Pos: key.Pos(),
+                    Code: ErrDuplicateKey,
+                    Found: p.curToken,
+                    Msg: fmt.Sprintf("duplicate key %s in hash literal", key.String()),
+                })
+            }
+            seen[constant] = true
+        }
+
+        if !p.expectPeek(token.Colon) {
+            return nil
+        }
+
+        p.nextToken()
+        value := p.parseExpression(LOWEST)
+        hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+        if !p.peekTokenIs(token.RBrace) && !p.expectPeek(token.Comma) {
+            return nil
+        }
+    }
+
+    if !p.expectPeek(token.RBrace) {
+        return nil
+    }
+    hash.RBrace = p.curToken
+
+    return hash
+}
+
+// Identifies keys whose value is known while parsing. Integers written
+// differently, like 16 and 0x10, are the same key.
+func constantKey(exp ast.Expression) (string, bool) {
+    switch exp := exp.(type) {
+    case *ast.IntegerLiteral:
+        return fmt.Sprintf("int:%d", exp.Value), true
+    case *ast.FloatLiteral:
+        return fmt.Sprintf("float:%v", exp.Value), true
+    case *ast.StringLiteral:
+        return "string:" + exp.Value, true
+    case *ast.Boolean:
+        return fmt.Sprintf("bool:%t", exp.Value), true
+    case *ast.PrefixExpression:
+        if lit, ok := exp.Right.(*ast.IntegerLiteral); ok && exp.Operator == "-" {
+            return fmt.Sprintf("int:%d", -lit.Value), true
+        }
+    }
+
+    return "", false
+}
+
+// Parses a[i] as well as the slices a[low:high], a[low:], a[:high] and a[:]
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+    open := p.curToken
+    var low ast.Expression
+
+    if !p.peekTokenIs(token.Colon) {
+        p.nextToken()
+        low = p.parseExpression(LOWEST)
+
+        if !p.peekTokenIs(token.Colon) {
+            if !p.expectPeek(token.RBracket) {
+                return nil
+            }
+            return &ast.IndexExpression{Token: open, Left: left, Index: low, RBracket: p.curToken}
+        }
+    }
+
+    slice := &ast.SliceExpression{Token: open, Left: left, Low: low}
+    p.nextToken()
+
+    if !p.peekTokenIs(token.RBracket) {
+        p.nextToken()
+        slice.High = p.parseExpression(LOWEST)
+    }
+
+    if !p.expectPeek(token.RBracket) {
+        return nil
+    }
+    slice.RBracket = p.curToken
+
+    return slice
+}
+
+// Parses a comma separated list of expressions up to the end token, leaving
+// curToken on it. A trailing comma is allowed.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+    list := []ast.Expression{}
+
+    if p.peekTokenIs(end) {
+        p.nextToken()
+        return list
+    }
+
+    p.nextToken()
+    list = append(list, p.parseExpression(LOWEST))
+
+    for p.peekTokenIs(token.Comma) {
+        p.nextToken()
+        if p.peekTokenIs(end) {
+            break
+        }
+        p.nextToken()
+        list = append(list, p.parseExpression(LOWEST))
+    }
+
+    if !p.expectPeek(end) {
+        return nil
+    }
+
+    return list
+}
+
+// Only names and index expressions can be assigned to. Any other target is
+// reported, but the assignment is still parsed.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+    exp := &ast.AssignExpression{
+        Token: p.curToken,
+        Target: target,
+        Operator: p.curToken.Literal,
+    }
+
+    // While recovering the target may be missing parts, and the error that
+    // broke it has been reported already
+    switch target.(type) {
+    case *ast.Identifier, *ast.IndexExpression:
+    default:
+        if target != nil && !p.panicking {
+            p.addCheckError(&ParseError{
+                Pos: target.Pos(),
+                Code: ErrInvalidAssignment,
+                Found: p.curToken,
+                Msg: fmt.Sprintf("cannot assign to %s", target.String()),
+            })
+        }
+    }
+
+    p.nextToken()
+    exp.Value = p.parseExpression(ASSIGN - 1)
+
+    return exp
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
     return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// The error messages, each prefixed with the position it refers to
 func (p *Parser) Errors() []string {
+    msgs := make([]string, len(p.errors))
+    for i, err := range p.errors {
+        msgs[i] = err.Error()
+    }
+    return msgs
+}
+
+func (p *Parser) ParseErrors() []*ParseError {
     return p.errors
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-    msg := fmt.Sprintf("Expected %s , got %s instead", 
-    t, p.peekToken.Type)
-    p.errors = append(p.errors, msg)
+// Incomplete reports whether the input only failed to parse because it
+// ended too early: inside an unclosed (, { or [, after an infix operator,
+// or in an unterminated raw string or block comment. Appending more input
+// may still make it valid.
+func (p *Parser) Incomplete() bool {
+    for _, err := range p.errors {
+        if err.Code != ErrIncomplete {
+            return false
+        }
+    }
+    return len(p.errors) > 0
+}
+
+// Records err unless we are already recovering from a previous one, so a
+// single mistake does not cascade into a pile of follow-up errors
+func (p *Parser) addError(err *ParseError) {
+    if p.panicking {
+        return
+    }
+    p.panicking = true
+    p.errors = append(p.errors, err)
+}
+
+// Records an error that leaves the syntax intact, like a duplicate hash key,
+// so parsing goes on without entering recovery
+func (p *Parser) addCheckError(err *ParseError) {
+    if p.panicking {
+        return
+    }
+    p.errors = append(p.errors, err)
+}
+
+func (p *Parser) peekError(expected ...token.TokenType) {
+    p.unexpectedError(p.peekToken, expected...)
+}
+
+func (p *Parser) unexpectedError(found token.Token, expected ...token.TokenType) {
+    code := ErrUnexpectedToken
+    if found.Type == token.EOF {
+        code = ErrIncomplete
+    }
+
+    p.addError(&ParseError{
+        Pos: found.Pos,
+        Code: code,
+        Expected: expected,
+        Found: found,
+        Msg: fmt.Sprintf("Expected %s , got %s instead", expectedString(expected), found.Type),
+    })
+}
+
+// Turns the lexer errors found while scanning the current token into parse
+// errors. They are always reported, and the statement containing them is
+// dropped.
+func (p *Parser) reportLexerErrors(errs []*lexer.Error) {
+    for _, err := range errs {
+        code := ErrLexical
+        if err.Incomplete {
+            code = ErrIncomplete
+        }
+
+        p.errors = append(p.errors, &ParseError{
+            Pos: err.Pos,
+            Code: code,
+            Found: p.curToken,
+            Msg: err.Msg,
+        })
+        p.panicking = true
+    }
+}
+
+// Skips tokens until the end of the broken statement: just after a `;`, or
+// right before a `let`, `return` or the `}` closing the current block.
+// Braces opened while skipping are balanced. The caller's nextToken() then
+// lands on the start of the next statement. If the broken statement already
+// consumed the closing `}` of its block, blockClosed tells the block to stop.
+func (p *Parser) synchronize() {
+    // Lexer errors met while skipping must not leak into the next statement
+    defer func() { p.panicking = false }()
+
+    for !p.curTokenIs(token.EOF) {
+        if p.braceDepth < p.blockLevel {
+            p.blockClosed = true
+            return
+        }
+
+        if p.braceDepth == p.blockLevel {
+            if p.curTokenIs(token.SemiColon) {
+                return
+            }
+
+            switch p.peekToken.Type {
+            case token.Let, token.Return, token.While, token.For,
+                token.Break, token.Continue, token.EOF:
+                return
+            case token.RBrace:
+                if p.blockLevel > 0 {
+                    return
+                }
+            }
+        }
+
+        p.nextToken()
+    }
 }
 
 func (p *Parser) nextToken() {
     p.curToken = p.peekToken
+    curLexErrors := p.peekLexErrors
+
     p.peekToken = p.l.NextToken()
+    p.comments = append(p.comments, p.peekToken.Leading...)
+    p.comments = append(p.comments, p.peekToken.Trailing...)
+
+    errs := p.l.Errors()
+    p.peekLexErrors = errs[p.lexErrors:]
+    p.lexErrors = len(errs)
+
+    p.reportLexerErrors(curLexErrors)
+
+    switch p.curToken.Type {
+    case token.LBrace:
+        p.braceDepth += 1
+    case token.RBrace:
+        p.braceDepth -= 1
+        p.lastRBrace = p.curToken
+    }
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -146,7 +672,7 @@ func (p *Parser) ParseProgram() *ast.Program {
     program.Statements = []ast.Statement{}
 
     for p.curToken.Type != token.EOF {
-        stmt := p.parseStatement()
+        stmt := p.parseStatementOrRecover()
 
         if stmt != nil {
             program.Statements = append(program.Statements, stmt)
@@ -154,16 +680,46 @@ func (p *Parser) ParseProgram() *ast.Program {
         p.nextToken()
     }
 
+    program.Comments = p.comments
     return program
 }
 
+// Parses one statement. If it fails the statement is dropped and the parser
+// skips to the next statement boundary. Errors already recovered from inside
+// a nested block do not affect the enclosing statement.
+func (p *Parser) parseStatementOrRecover() ast.Statement {
+    stmt := p.parseStatement()
+
+    if p.panicking {
+        p.synchronize()
+        return nil
+    }
+
+    return stmt
+}
+
 func (p *Parser) parseStatement() ast.Statement {
     switch p.curToken.Type {
 
     case token.Let: 
-        return p.parseLetStatement()
+        // Avoid wrapping a nil *ast.LetStatement in a non-nil interface
+        if stmt := p.parseLetStatement(); stmt != nil {
+            return stmt
+        }
+        return nil
     case token.Return:
         return p.parseReturnStatement()
+    case token.While:
+        return p.parseWhileStatement(nil)
+    case token.For:
+        return p.parseForStatement(nil)
+    case token.Break, token.Continue:
+        return p.parseBranchStatement()
+    case token.Ident:
+        if p.peekTokenIs(token.Colon) {
+            return p.parseLabeledStatement()
+        }
+        return p.parseExpressionStatement()
 
     default:
         return p.parseExpressionStatement()
@@ -204,10 +760,19 @@ func (p *Parser) parseExpression(prec int) ast.Expression {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement{
     stmt := &ast.ReturnStatement{Token: p.curToken}
+
+    // A bare `return` has no value
+    if p.peekTokenIs(token.SemiColon) || p.peekTokenIs(token.RBrace) || p.peekTokenIs(token.EOF) {
+        if p.peekTokenIs(token.SemiColon) {
+            p.nextToken()
+        }
+        return stmt
+    }
+
     p.nextToken()
+    stmt.ReturnValue = p.parseExpression(LOWEST)
 
-    // TODO: Actually parse any thing not just skip
-    for !p.curTokenIs(token.SemiColon) {
+    if p.peekTokenIs(token.SemiColon) {
         p.nextToken()
     }
 
@@ -218,14 +783,40 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
     lit := &ast.IntegerLiteral{Token: p.curToken}
     value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
     if err != nil {
-        msg := fmt.Sprintf("couldn't parse %q as integer", p.curToken.Literal)
-        p.errors = append(p.errors, msg)
+        p.numberError(err, "integer")
+        return nil
+    }
+    lit.Value = value
+    return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+    lit := &ast.FloatLiteral{Token: p.curToken}
+    value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+    if err != nil {
+        p.numberError(err, "float")
         return nil
     }
     lit.Value = value
     return lit
 }
 
+// Malformed literals are already reported by the lexer, so what is left to
+// catch here is mostly values that do not fit in 64 bits
+func (p *Parser) numberError(err error, kind string) {
+    msg := fmt.Sprintf("couldn't parse %q as %s", p.curToken.Literal, kind)
+    if errors.Is(err, strconv.ErrRange) {
+        msg = fmt.Sprintf("%s literal %s is out of range", kind, p.curToken.Literal)
+    }
+
+    p.addError(&ParseError{
+        Pos: p.curToken.Pos,
+        Code: ErrInvalidNumber,
+        Found: p.curToken,
+        Msg: msg,
+    })
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement{
     stmt := &ast.LetStatement{Token: p.curToken}
     if !p.expectPeek(token.Ident) {
@@ -238,14 +829,154 @@ func (p *Parser) parseLetStatement() *ast.LetStatement{
         return nil
     }
 
-    // TODO: Actually parse any thing not just skip
-    for !p.curTokenIs(token.SemiColon) {
+    p.nextToken()
+    stmt.Value = p.parseExpression(LOWEST)
+
+    if p.peekTokenIs(token.SemiColon) {
         p.nextToken()
     }
 
     return stmt
 }
 
+// Only loops can be labeled, e.g. `outer: for (x in xs) { ... }`
+func (p *Parser) parseLabeledStatement() ast.Statement {
+    label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    p.nextToken()
+
+    switch p.peekToken.Type {
+    case token.While:
+        p.nextToken()
+        return p.parseWhileStatement(label)
+    case token.For:
+        p.nextToken()
+        return p.parseForStatement(label)
+    default:
+        p.peekError(token.While, token.For)
+        return nil
+    }
+}
+
+func (p *Parser) parseWhileStatement(label *ast.Identifier) ast.Statement {
+    stmt := &ast.WhileStatement{Token: p.curToken, Label: label}
+
+    p.nextToken()
+    stmt.Condition = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.LBrace) {
+        return nil
+    }
+    stmt.Body = p.parseLoopBody(label)
+
+    if p.peekTokenIs(token.SemiColon) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+// Parses `for (k, v in iterable) { ... }`. Like the condition of an if the
+// parentheses are optional.
+func (p *Parser) parseForStatement(label *ast.Identifier) ast.Statement {
+    stmt := &ast.ForStatement{Token: p.curToken, Label: label}
+
+    parens := p.peekTokenIs(token.LParen)
+    if parens {
+        p.nextToken()
+    }
+
+    if !p.expectPeek(token.Ident) {
+        return nil
+    }
+    stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if p.peekTokenIs(token.Comma) {
+        p.nextToken()
+        if !p.expectPeek(token.Ident) {
+            return nil
+        }
+        stmt.Key = stmt.Value
+        stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    }
+
+    if !p.expectPeek(token.In) {
+        return nil
+    }
+
+    p.nextToken()
+    stmt.Iterable = p.parseExpression(LOWEST)
+
+    if parens && !p.expectPeek(token.RParen) {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBrace) {
+        return nil
+    }
+    stmt.Body = p.parseLoopBody(label)
+
+    if p.peekTokenIs(token.SemiColon) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseLoopBody(label *ast.Identifier) *ast.BlockStatement {
+    name := ""
+    if label != nil {
+        name = label.Value
+    }
+
+    p.loops = append(p.loops, name)
+    defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+
+    return p.parseBlockStatement()
+}
+
+// Parses break and continue. A label has to be on the same line, so that a
+// bare `break` can be followed by an expression statement.
+func (p *Parser) parseBranchStatement() ast.Statement {
+    tok := p.curToken
+
+    var label *ast.Identifier
+    if p.peekTokenIs(token.Ident) && p.peekToken.Pos.Line == tok.Pos.Line {
+        p.nextToken()
+        label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    }
+
+    p.checkBranch(tok, label)
+
+    if p.peekTokenIs(token.SemiColon) {
+        p.nextToken()
+    }
+
+    if tok.Type == token.Break {
+        return &ast.BreakStatement{Token: tok, Label: label}
+    }
+    return &ast.ContinueStatement{Token: tok, Label: label}
+}
+
+func (p *Parser) checkBranch(tok token.Token, label *ast.Identifier) {
+    msg := fmt.Sprintf("%s is not in a loop", tok.Literal)
+    if label != nil {
+        msg = fmt.Sprintf("%s label %s is not defined", tok.Literal, label.Value)
+    }
+
+    for _, name := range p.loops {
+        if label == nil || name == label.Value {
+            return
+        }
+    }
+
+    p.addCheckError(&ParseError{
+        Pos: tok.Pos,
+        Code: ErrInvalidBranch,
+        Found: tok,
+        Msg: msg,
+    })
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
     return p.curToken.Type == t
 }
@@ -265,7 +996,16 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) noPrefixParseError(t token.TokenType) {
-    msg := fmt.Sprintf("no prefix parser function for %s found", t)
-    p.errors = append(p.errors, msg)
+    code := ErrNoPrefixParser
+    if t == token.EOF {
+        code = ErrIncomplete
+    }
+
+    p.addError(&ParseError{
+        Pos: p.curToken.Pos,
+        Code: code,
+        Found: p.curToken,
+        Msg: fmt.Sprintf("no prefix parser function for %s found", t),
+    })
 }
 
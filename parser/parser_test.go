@@ -4,77 +4,154 @@ import (
 	"fmt"
 	"monkeylang/ast"
 	"monkeylang/lexer"
+	"monkeylang/token"
 	"testing"
 )
 
 func TestReturnStatements(t *testing.T) {
-    input := `
-    return 5;
-    return 10;
-    return 23234;
-    `
+    tests := []struct {
+        input string
+        expectedValue interface{}
+    } {
+        {"return 5;", 5},
+        {"return 10", 10},
+        {"return foobar;", "foobar"},
+        {"return 2 + 3;", "(2 + 3)"},
+    }
 
-    l := lexer.New(input)
-    p := New(l)
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
 
-    prog := p.ParseProgram()
-    checkParseErrors(t, p)
+        prog := p.ParseProgram()
+        checkParseErrors(t, p)
 
-    if len(prog.Statements) != 3 {
-        t.Fatalf("Program does not have 3 statements. got %d", len(prog.Statements))
-    }
+        if len(prog.Statements) != 1 {
+            t.Fatalf("Program does not have 1 statement. got %d", len(prog.Statements))
+        }
 
-    for _, stmt := range prog.Statements {
-        returnStmt, ok := stmt.(*ast.ReturnStatement)
+        returnStmt, ok := prog.Statements[0].(*ast.ReturnStatement)
         if !ok {
-            t.Errorf("statement is not a return statement got %q", stmt)
-            continue
+            t.Fatalf("statement is not a return statement got %T", prog.Statements[0])
         }
 
         if returnStmt.TokenLiteral() != "return" {
             t.Errorf(
                 "return statement Literal expected got %q",
                 returnStmt.TokenLiteral())
-            }
         }
+
+        if !testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+            return
+        }
+    }
+}
+
+func TestBareReturnStatement(t *testing.T) {
+    l := lexer.New("return;")
+    p := New(l)
+    prog := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    if len(prog.Statements) != 1 {
+        t.Fatalf("Program does not have 1 statement. got %d", len(prog.Statements))
+    }
+
+    returnStmt, ok := prog.Statements[0].(*ast.ReturnStatement)
+    if !ok {
+        t.Fatalf("statement is not a return statement got %T", prog.Statements[0])
     }
 
-    func TestLetStatements(t *testing.T) {
-        input := `
-        let x =  5;
-        let y = 10 ;
-        let foobar = 100030404;
-        `
-        l := lexer.New(input)
+    if returnStmt.ReturnValue != nil {
+        t.Errorf("expected no return value got %T", returnStmt.ReturnValue)
+    }
+
+    if prog.String() != "return;" {
+        t.Errorf("prog.String() is wrong got %q", prog.String())
+    }
+}
+
+func TestLetStatements(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedIdent string
+        expectedValue interface{}
+    } {
+        {"let x =  5;", "x", 5},
+        {"let y = 10 ;", "y", 10},
+        {"let foobar = y;", "foobar", "y"},
+        {"let z = 1 + 2", "z", "(1 + 2)"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
         p := New(l)
         program := p.ParseProgram()
         checkParseErrors(t, p)
         if program == nil {
-
             t.Fatalf("Program Parser returned nil")
         }
 
-        if len(program.Statements) != 3 {
-            t.Fatalf("Program does not contain 3 statements got %d", 
+        if len(program.Statements) != 1 {
+            t.Fatalf("Program does not contain 1 statement got %d", 
             len(program.Statements))
         }
 
-        tests := []struct {
-            expectedIdent string
-        } {
-            {"x"},
-            {"y"},
-            {"foobar"},
+        stmt := program.Statements[0]
+        if !testLetStatement(t, stmt, tt.expectedIdent) {
+            return
         }
 
-        for i, tt := range tests {
-            stmt := program.Statements[i]
-            if !testLetStatement(t, stmt, tt.expectedIdent) {
-                return
-            }
+        val := stmt.(*ast.LetStatement).Value
+        if !testLiteralExpression(t, val, tt.expectedValue) {
+            return
+        }
+    }
+}
+
+func TestLetAndReturnRoundTrip(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"let x = 1 + 2;", "let x = (1 + 2);"},
+        {"let y = -a * b", "let y = ((-a) * b);"},
+        {"return x; return;", "return x;return;"},
+        {"let a = 1; let b = a", "let a = 1;let b = a;"},
+        {"let m = if (a < b) { a } else { b };", "let m = if (a < b) { a } else { b };"},
+        {"if x { return; }", "if x { return; }"},
+        {"let add = fn(a, b) { a + b }; add(1, add(2, 3))", "let add = fn(a, b) { (a + b) };add(1, add(2, 3))"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("program.String() expected %q got %q", tt.expected, program.String())
+        }
+
+        // Parsing the rendered output must give the same rendering again
+        p = New(lexer.New(program.String()))
+        again := p.ParseProgram()
+        checkParseErrors(t, p)
+        if again.String() != tt.expected {
+            t.Errorf("round trip expected %q got %q", tt.expected, again.String())
         }
     }
+}
+
+func TestLetStatementMissingValue(t *testing.T) {
+    l := lexer.New("let x =")
+    p := New(l)
+    p.ParseProgram()
 
+    if len(p.Errors()) == 0 {
+        t.Fatalf("expected a parse error for a let without a value")
+    }
+}
 
     func checkParseErrors(t *testing.T, p *Parser) {
         errors := p.Errors()
@@ -321,6 +398,39 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
      { "5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))", },
      { "5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))", },
      { "3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))", },
+     { "true", "true", },
+     { "3 > 5 == false", "((3 > 5) == false)", },
+     { "1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)", },
+     { "(5 + 5) * 2", "((5 + 5) * 2)", },
+     { "2 / (5 + 5)", "(2 / (5 + 5))", },
+     { "-(5 + 5)", "(-(5 + 5))", },
+     { "!(true == true)", "(!(true == true))", },
+     { "a + add(b * c) + d", "((a + add((b * c))) + d)", },
+     { "add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))", },
+     { "add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))", },
+     { "fn(x) { x }(5)", "fn(x) { x }(5)", },
+     { "a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)", },
+     { "add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))", },
+     { "fns[0](x)", "(fns[0])(x)", },
+     { "m[k][j]", "((m[k])[j])", },
+     { "-a[1]", "(-(a[1]))", },
+     { "a[1:3][0]", "((a[1:3])[0])", },
+     { "a <= b == c >= d", "((a <= b) == (c >= d))", },
+     { "a || b && c", "(a || (b && c))", },
+     { "a && b || c && d", "((a && b) || (c && d))", },
+     { "a == b && c != d", "((a == b) && (c != d))", },
+     { "a + b % c", "(a + (b % c))", },
+     { "a ** b ** c", "(a ** (b ** c))", },
+     { "a * b ** c", "(a * (b ** c))", },
+     { "-a ** b", "(-(a ** b))", },
+     { "a ** -b", "(a ** (-b))", },
+     { "a - b - c", "((a - b) - c)", },
+     { "a | b ^ c & d", "(a | (b ^ (c & d)))", },
+     { "a & b == c", "((a & b) == c)", },
+     { "a << b + c", "(a << (b + c))", },
+     { "a | b < c", "((a | b) < c)", },
+     { "~a & b", "((~a) & b)", },
+     { "a >> b << c", "((a >> b) << c)", },
 
     }
 
@@ -336,3 +446,1140 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 
     }
 }
+
+func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
+    ident, ok := exp.(*ast.Identifier)
+    if !ok {
+        t.Errorf("exp is not an *ast.Identifier got %T", exp)
+        return false
+    }
+
+    if ident.Value != value {
+        t.Errorf("ident.Value is not %s got %s", value, ident.Value)
+        return false
+    }
+
+    if ident.TokenLiteral() != value {
+        t.Errorf("ident.TokenLiteral is not %s got %s", value, ident.TokenLiteral())
+        return false
+    }
+
+    return true
+}
+
+// Identifiers are matched by name, anything else by its String() rendering
+func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) bool {
+    switch v := expected.(type) {
+    case int:
+        return testIntegerLiteral(t, exp, int64(v))
+    case int64:
+        return testIntegerLiteral(t, exp, v)
+    case bool:
+        return testBooleanLiteral(t, exp, v)
+    case string:
+        if _, ok := exp.(*ast.Identifier); ok {
+            return testIdentifier(t, exp, v)
+        }
+        if exp == nil || exp.String() != v {
+            t.Errorf("exp is not %q got %v", v, exp)
+            return false
+        }
+        return true
+    }
+
+    t.Errorf("type of exp not handled got %T", exp)
+    return false
+}
+
+func TestNodePositions(t *testing.T) {
+    input := "let x = 1;\nreturn -a * bb;"
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    if len(program.Statements) != 2 {
+        t.Fatalf("program does not have 2 statements got %d", len(program.Statements))
+    }
+
+    tests := []struct {
+        node ast.Node
+        pos string
+        end string
+    } {
+        {program, "1:1", "2:15"},
+        {program.Statements[0], "1:1", "1:10"},
+        {program.Statements[1], "2:1", "2:15"},
+        {program.Statements[1].(*ast.ReturnStatement).ReturnValue, "2:8", "2:15"},
+    }
+
+    for i, tt := range tests {
+        if tt.node.Pos().String() != tt.pos {
+            t.Errorf("tests[%d] Pos() expected %s got %s", i, tt.pos, tt.node.Pos())
+        }
+        if tt.node.End().String() != tt.end {
+            t.Errorf("tests[%d] End() expected %s got %s", i, tt.end, tt.node.End())
+        }
+    }
+}
+
+func TestErrorPositions(t *testing.T) {
+    input := "let x = 5;\nlet y 10;"
+    p := New(lexer.NewFile("script.mk", input))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) == 0 {
+        t.Fatalf("expected parse errors")
+    }
+
+    expected := "script.mk:2:7: Expected = , got INT instead"
+    if errors[0] != expected {
+        t.Errorf("errors[0] expected %q got %q", expected, errors[0])
+    }
+}
+
+func TestErrorRecovery(t *testing.T) {
+    input := `
+    let x 5;
+    let y = 10;
+    let = 3 + ;
+    return y;
+    let z = ) + ) * );
+    z;
+    `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    expected := []struct {
+        code ErrorCode
+        line int
+        found string
+    } {
+        {ErrUnexpectedToken, 2, "5"},
+        {ErrUnexpectedToken, 4, "="},
+        {ErrNoPrefixParser, 6, ")"},
+    }
+
+    errors := p.ParseErrors()
+    if len(errors) != len(expected) {
+        t.Fatalf("expected %d errors got %d: %v", len(expected), len(errors), p.Errors())
+    }
+
+    for i, tt := range expected {
+        err := errors[i]
+        if err.Code != tt.code {
+            t.Errorf("errors[%d].Code expected %s got %s", i, tt.code, err.Code)
+        }
+        if err.Pos.Line != tt.line {
+            t.Errorf("errors[%d].Pos.Line expected %d got %d", i, tt.line, err.Pos.Line)
+        }
+        if err.Found.Literal != tt.found {
+            t.Errorf("errors[%d].Found expected %q got %q", i, tt.found, err.Found.Literal)
+        }
+    }
+
+    if len(errors[0].Expected) != 1 || errors[0].Expected[0] != token.Assign {
+        t.Errorf("errors[0].Expected is wrong got %v", errors[0].Expected)
+    }
+
+    // The valid statements survive as a partial program
+    if program.String() != "let y = 10;return y;z" {
+        t.Errorf("partial program is wrong got %q", program.String())
+    }
+}
+
+func TestParseErrorMessages(t *testing.T) {
+    p := New(lexer.New("let 5"))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) != 1 {
+        t.Fatalf("expected 1 error got %d: %v", len(errors), errors)
+    }
+
+    if errors[0] != "1:5: Expected IDENT , got INT instead" {
+        t.Errorf("error message is wrong got %q", errors[0])
+    }
+}
+
+func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
+    b, ok := exp.(*ast.Boolean)
+    if !ok {
+        t.Errorf("exp is not an *ast.Boolean got %T", exp)
+        return false
+    }
+
+    if b.Value != value {
+        t.Errorf("b.Value is not %t got %t", value, b.Value)
+        return false
+    }
+
+    if b.TokenLiteral() != fmt.Sprintf("%t", value) {
+        t.Errorf("b.TokenLiteral is not %t got %s", value, b.TokenLiteral())
+        return false
+    }
+
+    return true
+}
+
+func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, operator string, right interface{}) bool {
+    opExp, ok := exp.(*ast.InfixExpression)
+    if !ok {
+        t.Errorf("exp is not an *ast.InfixExpression got %T(%s)", exp, exp)
+        return false
+    }
+
+    if !testLiteralExpression(t, opExp.Left, left) {
+        return false
+    }
+
+    if opExp.Operator != operator {
+        t.Errorf("exp.Operator is not %q got %q", operator, opExp.Operator)
+        return false
+    }
+
+    if !testLiteralExpression(t, opExp.Right, right) {
+        return false
+    }
+
+    return true
+}
+
+func TestBooleanExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    } {
+        {"true;", true},
+        {"false;", false},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program does not have 1 statement got %d", len(program.Statements))
+        }
+
+        stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            t.Fatalf("program.Statements[0] is not an ExpressionStatement got %T",
+            program.Statements[0])
+        }
+
+        testBooleanLiteral(t, stmt.Expression, tt.expected)
+    }
+}
+
+func TestIfExpression(t *testing.T) {
+    tests := []string {
+        "if (x < y) { x }",
+        "if x < y { x }",
+    }
+
+    for _, input := range tests {
+        p := New(lexer.New(input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program does not have 1 statement got %d", len(program.Statements))
+        }
+
+        stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            t.Fatalf("program.Statements[0] is not an ExpressionStatement got %T",
+            program.Statements[0])
+        }
+
+        exp, ok := stmt.Expression.(*ast.IfExpression)
+        if !ok {
+            t.Fatalf("stmt.Expression is not an *ast.IfExpression got %T", stmt.Expression)
+        }
+
+        if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+            return
+        }
+
+        if len(exp.Consequence.Statements) != 1 {
+            t.Fatalf("consequence does not have 1 statement got %d",
+            len(exp.Consequence.Statements))
+        }
+
+        consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            t.Fatalf("Statements[0] is not an ExpressionStatement got %T",
+            exp.Consequence.Statements[0])
+        }
+
+        if !testIdentifier(t, consequence.Expression, "x") {
+            return
+        }
+
+        if exp.Alternative != nil {
+            t.Errorf("exp.Alternative was not nil got %+v", exp.Alternative)
+        }
+    }
+}
+
+func TestIfElseExpression(t *testing.T) {
+    input := "if (x < y) { x } else { y }"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program does not have 1 statement got %d", len(program.Statements))
+    }
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp, ok := stmt.Expression.(*ast.IfExpression)
+    if !ok {
+        t.Fatalf("stmt.Expression is not an *ast.IfExpression got %T", stmt.Expression)
+    }
+
+    if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+        return
+    }
+
+    if len(exp.Consequence.Statements) != 1 || len(exp.Alternative.Statements) != 1 {
+        t.Fatalf("branches do not have 1 statement each")
+    }
+
+    consequence := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+    if !testIdentifier(t, consequence.Expression, "x") {
+        return
+    }
+
+    alternative := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+    if !testIdentifier(t, alternative.Expression, "y") {
+        return
+    }
+
+    if exp.End().String() != "1:28" {
+        t.Errorf("exp.End() expected 1:28 got %s", exp.End())
+    }
+}
+
+func TestUnclosedBlock(t *testing.T) {
+    p := New(lexer.New("if (x) { let y = 1;"))
+    p.ParseProgram()
+
+    errors := p.ParseErrors()
+    if len(errors) != 1 {
+        t.Fatalf("expected 1 error got %d: %v", len(errors), p.Errors())
+    }
+
+    if errors[0].Found.Type != token.EOF || errors[0].Expected[0] != token.RBrace {
+        t.Errorf("error is wrong got %q", errors[0].Error())
+    }
+}
+
+func TestUnclosedGroup(t *testing.T) {
+    p := New(lexer.New("(1 + 2;"))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) != 1 || errors[0] != "1:7: Expected ) , got ; instead" {
+        t.Errorf("errors are wrong got %v", errors)
+    }
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+    input := "fn(x, y) { x + y; }"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program does not have 1 statement got %d", len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not an ExpressionStatement got %T",
+        program.Statements[0])
+    }
+
+    function, ok := stmt.Expression.(*ast.FunctionLiteral)
+    if !ok {
+        t.Fatalf("stmt.Expression is not an *ast.FunctionLiteral got %T", stmt.Expression)
+    }
+
+    if len(function.Parameters) != 2 {
+        t.Fatalf("function literal parameters wrong want 2 got %d", len(function.Parameters))
+    }
+
+    testLiteralExpression(t, function.Parameters[0], "x")
+    testLiteralExpression(t, function.Parameters[1], "y")
+
+    if len(function.Body.Statements) != 1 {
+        t.Fatalf("function.Body.Statements does not have 1 statement got %d",
+        len(function.Body.Statements))
+    }
+
+    bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("function body stmt is not an ExpressionStatement got %T",
+        function.Body.Statements[0])
+    }
+
+    testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedParams []string
+    } {
+        {"fn() {};", []string{}},
+        {"fn(x) {};", []string{"x"}},
+        {"fn(x, y, z) {};", []string{"x", "y", "z"}},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        function := stmt.Expression.(*ast.FunctionLiteral)
+
+        if len(function.Parameters) != len(tt.expectedParams) {
+            t.Errorf("length of parameters wrong want %d got %d",
+            len(tt.expectedParams), len(function.Parameters))
+        }
+
+        for i, ident := range tt.expectedParams {
+            testLiteralExpression(t, function.Parameters[i], ident)
+        }
+    }
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+    input := "add(1, 2 * 3, 4 + 5);"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program does not have 1 statement got %d", len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not an ExpressionStatement got %T",
+        program.Statements[0])
+    }
+
+    exp, ok := stmt.Expression.(*ast.CallExpression)
+    if !ok {
+        t.Fatalf("stmt.Expression is not an *ast.CallExpression got %T", stmt.Expression)
+    }
+
+    if !testIdentifier(t, exp.Function, "add") {
+        return
+    }
+
+    if len(exp.Arguments) != 3 {
+        t.Fatalf("wrong length of arguments got %d", len(exp.Arguments))
+    }
+
+    testLiteralExpression(t, exp.Arguments[0], 1)
+    testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+    testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+
+    if exp.End().String() != "1:21" {
+        t.Errorf("exp.End() expected 1:21 got %s", exp.End())
+    }
+}
+
+func TestImmediatelyInvokedFunction(t *testing.T) {
+    p := New(lexer.New("fn(x){x}(5)"))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp, ok := stmt.Expression.(*ast.CallExpression)
+    if !ok {
+        t.Fatalf("stmt.Expression is not an *ast.CallExpression got %T", stmt.Expression)
+    }
+
+    if _, ok := exp.Function.(*ast.FunctionLiteral); !ok {
+        t.Fatalf("exp.Function is not an *ast.FunctionLiteral got %T", exp.Function)
+    }
+
+    if len(exp.Arguments) != 1 || !testLiteralExpression(t, exp.Arguments[0], 5) {
+        t.Errorf("wrong arguments got %v", exp.Arguments)
+    }
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+    tests := []string {
+        "fn(1) {}",
+        "fn(x,) {}",
+        "fn(x y) {}",
+        "add(1, 2",
+        "let f = fn(x) { x + }; f(1)",
+        "if (x) { let y = fn(1) { 2 }; y }",
+        "let g = fn() { if (x) { 1 + } }; g()",
+    }
+
+    for _, input := range tests {
+        p := New(lexer.New(input))
+        p.ParseProgram()
+
+        if len(p.Errors()) != 1 {
+            t.Errorf("%q expected 1 error got %v", input, p.Errors())
+        }
+    }
+}
+
+func TestRecoveryInsideBlocks(t *testing.T) {
+    input := `
+    let f = fn(x) {
+        let = 1;
+        x + 1
+    };
+    f(2);
+    `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 1 {
+        t.Fatalf("expected 1 error got %v", p.Errors())
+    }
+
+    // The broken statement is dropped, the surrounding ones are kept
+    expected := "let f = fn(x) { (x + 1) };f(2)"
+    if program.String() != expected {
+        t.Errorf("partial program expected %q got %q", expected, program.String())
+    }
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+    input := `"hello \"world\"\n";`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    literal, ok := stmt.Expression.(*ast.StringLiteral)
+    if !ok {
+        t.Fatalf("exp is not *ast.StringLiteral got %T", stmt.Expression)
+    }
+
+    if literal.Value != "hello \"world\"\n" {
+        t.Errorf("literal.Value wrong got %q", literal.Value)
+    }
+
+    // String() quotes the value so it can be parsed back
+    if literal.String() != `"hello \"world\"\n"` {
+        t.Errorf("literal.String() wrong got %q", literal.String())
+    }
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+    input := `
+    let a = "unterminated;
+    let b = 1;
+    let c = "bad \q escape";
+    let d = @;
+    let e = 2;
+    `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    expected := []string {
+        "2:13: unterminated string literal",
+        "4:18: unknown escape sequence \\q",
+        "5:13: illegal character '@'",
+    }
+
+    errors := p.ParseErrors()
+    if len(errors) != len(expected) {
+        t.Fatalf("expected %d errors got %v", len(expected), p.Errors())
+    }
+
+    for i, msg := range expected {
+        if errors[i].Error() != msg {
+            t.Errorf("errors[%d] expected %q got %q", i, msg, errors[i].Error())
+        }
+        if errors[i].Code != ErrLexical {
+            t.Errorf("errors[%d] code expected %s got %s", i, ErrLexical, errors[i].Code)
+        }
+    }
+
+    if program.String() != "let b = 1;let e = 2;" {
+        t.Errorf("partial program wrong got %q", program.String())
+    }
+}
+
+func TestNumberLiteralExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    } {
+        {"0x1F", int64(31)},
+        {"0o17", int64(15)},
+        {"0b1010", int64(10)},
+        {"1_000_000", int64(1000000)},
+        {"9223372036854775807", int64(9223372036854775807)},
+        {"1.5", 1.5},
+        {"2.5e-3", 0.0025},
+        {"1_000.5", 1000.5},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+        switch expected := tt.expected.(type) {
+        case int64:
+            if !testIntegerLiteralValue(t, stmt.Expression, expected) {
+                return
+            }
+        case float64:
+            lit, ok := stmt.Expression.(*ast.FloatLiteral)
+            if !ok {
+                t.Fatalf("exp is not *ast.FloatLiteral got %T", stmt.Expression)
+            }
+            if lit.Value != expected {
+                t.Errorf("lit.Value expected %g got %g", expected, lit.Value)
+            }
+            if lit.String() != tt.input {
+                t.Errorf("lit.String() expected %q got %q", tt.input, lit.String())
+            }
+        }
+    }
+}
+
+func testIntegerLiteralValue(t *testing.T, exp ast.Expression, value int64) bool {
+    lit, ok := exp.(*ast.IntegerLiteral)
+    if !ok {
+        t.Errorf("exp is not *ast.IntegerLiteral got %T", exp)
+        return false
+    }
+
+    if lit.Value != value {
+        t.Errorf("lit.Value expected %d got %d", value, lit.Value)
+        return false
+    }
+
+    return true
+}
+
+func TestNumberErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        code ErrorCode
+        expected string
+    } {
+        {"let x = 99999999999999999999;", ErrInvalidNumber, "1:9: integer literal 99999999999999999999 is out of range"},
+        {"let y = 1e400;", ErrInvalidNumber, "1:9: float literal 1e400 is out of range"},
+        {"let z = 0x;", ErrLexical, "1:9: hexadecimal literal has no digits"},
+        {"let w = 1e;", ErrLexical, "1:11: exponent has no digits"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.ParseErrors()
+        if len(errors) != 1 {
+            t.Errorf("%q expected 1 error got %v", tt.input, p.Errors())
+            continue
+        }
+
+        if errors[0].Code != tt.code || errors[0].Error() != tt.expected {
+            t.Errorf("%q expected %s %q got %s %q", tt.input, tt.code, tt.expected, errors[0].Code, errors[0].Error())
+        }
+    }
+}
+
+func TestCommentsAreIgnored(t *testing.T) {
+    input := `
+    // add two numbers
+    let add = fn(a, /* first */ b) {
+        a + b // sum
+    };
+    # call it
+    add(1, 2); /* done */
+    `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    expected := "let add = fn(a, b) { (a + b) };add(1, 2)"
+    if program.String() != expected {
+        t.Errorf("program expected %q got %q", expected, program.String())
+    }
+
+    comments := []string{"// add two numbers", "/* first */", "// sum", "# call it", "/* done */"}
+    if len(program.Comments) != len(comments) {
+        t.Fatalf("expected %d comments got %v", len(comments), program.Comments)
+    }
+
+    for i, text := range comments {
+        if program.Comments[i].Text != text {
+            t.Errorf("comments[%d] expected %q got %q", i, text, program.Comments[i].Text)
+        }
+    }
+
+    // The comment above the let statement is trivia of its first token
+    let := program.Statements[0].(*ast.LetStatement)
+    if len(let.Token.Leading) != 1 || let.Token.Leading[0].Text != "// add two numbers" {
+        t.Errorf("let.Token.Leading wrong got %v", let.Token.Leading)
+    }
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+    input := "[1, 2 * 2, 3 + 3]"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    array, ok := stmt.Expression.(*ast.ArrayLiteral)
+    if !ok {
+        t.Fatalf("exp is not *ast.ArrayLiteral got %T", stmt.Expression)
+    }
+
+    if len(array.Elements) != 3 {
+        t.Fatalf("len(array.Elements) not 3 got %d", len(array.Elements))
+    }
+
+    testIntegerLiteral(t, array.Elements[0], 1)
+    testInfixExpression(t, array.Elements[1], 2, "*", 2)
+    testInfixExpression(t, array.Elements[2], 3, "+", 3)
+
+    if array.End().String() != "1:18" {
+        t.Errorf("array.End() expected 1:18 got %s", array.End())
+    }
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+    p := New(lexer.New("[]"))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    array, ok := stmt.Expression.(*ast.ArrayLiteral)
+    if !ok {
+        t.Fatalf("exp is not *ast.ArrayLiteral got %T", stmt.Expression)
+    }
+
+    if len(array.Elements) != 0 {
+        t.Errorf("len(array.Elements) not 0 got %d", len(array.Elements))
+    }
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+    input := "myArray[1 + 1]"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+    if !ok {
+        t.Fatalf("exp is not *ast.IndexExpression got %T", stmt.Expression)
+    }
+
+    if !testIdentifier(t, indexExp.Left, "myArray") {
+        return
+    }
+
+    if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+        return
+    }
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        low interface{}
+        high interface{}
+        expected string
+    } {
+        {"a[1:3]", 1, 3, "(a[1:3])"},
+        {"a[:n]", nil, "n", "(a[:n])"},
+        {"a[2:]", 2, nil, "(a[2:])"},
+        {"a[:]", nil, nil, "(a[:])"},
+        {"a[-3:-1]", "(-3)", "(-1)", "(a[(-3):(-1)])"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        slice, ok := stmt.Expression.(*ast.SliceExpression)
+        if !ok {
+            t.Fatalf("%q exp is not *ast.SliceExpression got %T", tt.input, stmt.Expression)
+        }
+
+        if !testIdentifier(t, slice.Left, "a") {
+            return
+        }
+
+        for _, bound := range []struct {
+            exp ast.Expression
+            expected interface{}
+        } {{slice.Low, tt.low}, {slice.High, tt.high}} {
+            if bound.expected == nil {
+                if bound.exp != nil {
+                    t.Errorf("%q expected an omitted bound got %s", tt.input, bound.exp)
+                }
+                continue
+            }
+            testLiteralExpression(t, bound.exp, bound.expected)
+        }
+
+        if slice.String() != tt.expected {
+            t.Errorf("slice.String() expected %q got %q", tt.expected, slice.String())
+        }
+    }
+}
+
+func TestIndexErrors(t *testing.T) {
+    tests := []string {
+        "a[1",
+        "a[1:2",
+        "a[]",
+        "[1, 2",
+    }
+
+    for _, input := range tests {
+        p := New(lexer.New(input))
+        p.ParseProgram()
+
+        if len(p.Errors()) != 1 {
+            t.Errorf("%q expected 1 error got %v", input, p.Errors())
+        }
+    }
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+    input := `{"one": 1, "two": 2, "three": 3,}`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    hash, ok := stmt.Expression.(*ast.HashLiteral)
+    if !ok {
+        t.Fatalf("exp is not *ast.HashLiteral got %T", stmt.Expression)
+    }
+
+    expected := []struct {
+        key string
+        value int64
+    } {
+        {"one", 1},
+        {"two", 2},
+        {"three", 3},
+    }
+
+    if len(hash.Pairs) != len(expected) {
+        t.Fatalf("hash.Pairs has wrong length got %d", len(hash.Pairs))
+    }
+
+    for i, tt := range expected {
+        literal, ok := hash.Pairs[i].Key.(*ast.StringLiteral)
+        if !ok {
+            t.Errorf("key is not *ast.StringLiteral got %T", hash.Pairs[i].Key)
+            continue
+        }
+
+        if literal.Value != tt.key {
+            t.Errorf("key expected %q got %q", tt.key, literal.Value)
+        }
+
+        testIntegerLiteral(t, hash.Pairs[i].Value, tt.value)
+    }
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+    p := New(lexer.New("{}"))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    hash, ok := stmt.Expression.(*ast.HashLiteral)
+    if !ok {
+        t.Fatalf("exp is not *ast.HashLiteral got %T", stmt.Expression)
+    }
+
+    if len(hash.Pairs) != 0 {
+        t.Errorf("hash.Pairs has wrong length got %d", len(hash.Pairs))
+    }
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {`{"name": "x", 1: true, key(): v}`, `{"name": "x", 1: true, key(): v}`},
+        {`{"one": 0 + 1, "two": 10 - 8}`, `{"one": (0 + 1), "two": (10 - 8)}`},
+        {`{"a": {"b": [1, 2]}}["a"]["b"]`, `(({"a": {"b": [1, 2]}}["a"])["b"])`},
+        {"let f = fn() { {1: 2} }", "let f = fn() { {1: 2} };"},
+        {"if (x) { {} } else { {y: 1} }", "if x { {} } else { {y: 1} }"},
+        {"[1, 2,]", "[1, 2]"},
+        {"f(a, b,)", "f(a, b)"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("%q expected %q got %q", tt.input, tt.expected, program.String())
+        }
+    }
+}
+
+func TestHashLiteralErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        code ErrorCode
+        expected string
+    } {
+        {`{"a": 1, "a": 2}`, ErrDuplicateKey, `1:10: duplicate key "a" in hash literal`},
+        {`{16: 1, 0x10: 2}`, ErrDuplicateKey, `1:9: duplicate key 0x10 in hash literal`},
+        {`{-1: 1, true: 2, -1: 3}`, ErrDuplicateKey, `1:18: duplicate key (-1) in hash literal`},
+        {`{"a" 1}`, ErrUnexpectedToken, `1:6: Expected : , got INT instead`},
+        {`{"a": 1 "b": 2}`, ErrUnexpectedToken, `1:9: Expected , , got STRING instead`},
+        {`{"a": 1,,}`, ErrNoPrefixParser, `1:9: no prefix parser function for , found`},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.ParseErrors()
+        if len(errors) != 1 {
+            t.Errorf("%q expected 1 error got %v", tt.input, p.Errors())
+            continue
+        }
+
+        if errors[0].Code != tt.code || errors[0].Error() != tt.expected {
+            t.Errorf("%q expected %s %q got %s %q", tt.input, tt.code, tt.expected, errors[0].Code, errors[0].Error())
+        }
+    }
+}
+
+func TestDuplicateKeyKeepsStatement(t *testing.T) {
+    p := New(lexer.New(`let h = {"a": 1, "a": 2}; h`))
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 1 {
+        t.Fatalf("expected 1 error got %v", p.Errors())
+    }
+
+    if len(program.Statements) != 2 {
+        t.Errorf("expected 2 statements got %d", len(program.Statements))
+    }
+}
+
+func TestAssignExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"x = 5", "(x = 5)"},
+        {"a = b = c", "(a = (b = c))"},
+        {"x += 1 * 2", "(x += (1 * 2))"},
+        {"x -= y || z", "(x -= (y || z))"},
+        {"x *= 2; y /= 3; z %= 4", "(x *= 2)(y /= 3)(z %= 4)"},
+        {"a[i] = v", "((a[i]) = v)"},
+        {`h["k"] += 1`, `((h["k"]) += 1)`},
+        {"a[0][1] = x = 2", "(((a[0])[1]) = (x = 2))"},
+        {"(x) = 1", "(x = 1)"},
+        {"let y = x = 1;", "let y = (x = 1);"},
+        {"f(x = 1)", "f((x = 1))"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("%q expected %q got %q", tt.input, tt.expected, program.String())
+        }
+    }
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"1 = 2", "1:1: cannot assign to 1"},
+        {"a + b = c", "1:1: cannot assign to (a + b)"},
+        {"f() += 1", "1:1: cannot assign to f()"},
+        {"x = 1 = 2", "1:5: cannot assign to 1"},
+        {"let a = 1; a[0:1] = 2", "1:12: cannot assign to (a[0:1])"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.ParseErrors()
+        if len(errors) != 1 {
+            t.Errorf("%q expected 1 error got %v", tt.input, p.Errors())
+            continue
+        }
+
+        if errors[0].Code != ErrInvalidAssignment || errors[0].Error() != tt.expected {
+            t.Errorf("%q expected %q got %s %q", tt.input, tt.expected, errors[0].Code, errors[0].Error())
+        }
+    }
+}
+
+func TestLoopStatements(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    } {
+        {"while (x < 3) { x += 1 }", "while (x < 3) { (x += 1) }"},
+        {"while x { }", "while x { }"},
+        {"for (x in xs) { puts(x) }", "for x in xs { puts(x) }"},
+        {"for (k, v in h) { k; v }", "for k, v in h { kv }"},
+        {"for x in [1, 2] { }", "for x in [1, 2] { }"},
+        {"for (x in f(a)) { }", "for x in f(a) { }"},
+        {"while (true) { break; }", "while true { break; }"},
+        {"while (true) { continue }", "while true { continue; }"},
+        {"outer: for (x in xs) { for (y in ys) { break outer; } }",
+            "outer: for x in xs { for y in ys { break outer; } }"},
+        {"l: while (a) { while (b) { continue l } }", "l: while a { while b { continue l; } }"},
+        {"while (a) { break\nx }", "while a { break;x }"},
+        {"while (a) { }; 1", "while a { }1"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParseErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("%q expected %q got %q", tt.input, tt.expected, program.String())
+        }
+    }
+}
+
+func TestForStatementVariables(t *testing.T) {
+    p := New(lexer.New("for (k, v in h) { }"))
+    program := p.ParseProgram()
+    checkParseErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ForStatement)
+    if !ok {
+        t.Fatalf("expected *ast.ForStatement got %T", program.Statements[0])
+    }
+
+    if !testIdentifier(t, stmt.Key, "k") || !testIdentifier(t, stmt.Value, "v") {
+        return
+    }
+    testIdentifier(t, stmt.Iterable, "h")
+
+    p = New(lexer.New("for (v in h) { }"))
+    stmt = p.ParseProgram().Statements[0].(*ast.ForStatement)
+    if stmt.Key != nil {
+        t.Errorf("expected no key got %s", stmt.Key)
+    }
+    testIdentifier(t, stmt.Value, "v")
+}
+
+func TestLoopErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        code ErrorCode
+        expected string
+    } {
+        {"break", ErrInvalidBranch, "1:1: break is not in a loop"},
+        {"if (x) { continue; }", ErrInvalidBranch, "1:10: continue is not in a loop"},
+        {"while (x) { fn() { break } }", ErrInvalidBranch, "1:20: break is not in a loop"},
+        {"while (x) { break outer }", ErrInvalidBranch, "1:13: break label outer is not defined"},
+        {"a: while (x) { } while (y) { continue a }", ErrInvalidBranch, "1:30: continue label a is not defined"},
+        {"a: let x = 1", ErrUnexpectedToken, "1:4: Expected while or for , got Let instead"},
+        {"for (x y) { }", ErrUnexpectedToken, "1:8: Expected in , got IDENT instead"},
+        {"for (x in xs { }", ErrUnexpectedToken, "1:14: Expected ) , got { instead"},
+        {"for (1 in xs) { }", ErrUnexpectedToken, "1:6: Expected IDENT , got INT instead"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.ParseErrors()
+        if len(errors) != 1 {
+            t.Errorf("%q expected 1 error got %v", tt.input, p.Errors())
+            continue
+        }
+
+        if errors[0].Code != tt.code || errors[0].Error() != tt.expected {
+            t.Errorf("%q expected %s %q got %s %q", tt.input, tt.code, tt.expected, errors[0].Code, errors[0].Error())
+        }
+    }
+}
+
+func TestIncompleteInput(t *testing.T) {
+    tests := []struct {
+        input string
+        incomplete bool
+    } {
+        {"let f = fn(x) {", true},
+        {"let f = fn(x) {\n    x +", true},
+        {"(1 + 2", true},
+        {"[1, 2", true},
+        {`{"a": 1,`, true},
+        {"f(1,", true},
+        {"1 +", true},
+        {"a &&\n", true},
+        {"x = ", true},
+        {"let x =", true},
+        {"if (x) { 1 } else {", true},
+        {"while (x) { for (y in z) {", true},
+        {"`raw\nstring", true},
+        {"1 /* still", true},
+        {"1 + 2", false},
+        {"", false},
+        {"(1 + 2;", false},
+        {"let = 1", false},
+        {"1 + ) {", false},
+        {`"abc`, false},
+        {"let = 2; (", false},
+        {"}", false},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        if p.Incomplete() != tt.incomplete {
+            t.Errorf("%q expected Incomplete() %t got %t: %v", tt.input, tt.incomplete, p.Incomplete(), p.Errors())
+        }
+    }
+}
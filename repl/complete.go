@@ -0,0 +1,69 @@
+package repl
+
+import (
+    "monkeylang/token"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// Complete finds what the word just before the cursor may be completed to.
+// before is the text of the line up to the cursor. It returns the word
+// being completed, which is a suffix of before, and the sorted candidates
+// that start with it: REPL commands, mode names after :mode, and in code
+// keywords and the names bound in the session.
+func (s *Session) Complete(before string) (string, []string) {
+    trimmed := strings.TrimLeft(before, " \t")
+
+    if len(s.pending) == 0 && strings.HasPrefix(trimmed, ":") {
+        command, arg, spaced := strings.Cut(trimmed, " ")
+        if !spaced {
+            names := []string{}
+            for name := range metaCommands {
+                names = append(names, ":"+name)
+            }
+            return command, matching(command, names)
+        }
+
+        switch command {
+        case ":mode":
+            word := strings.TrimLeft(arg, " ")
+            if strings.Contains(word, " ") {
+                return "", nil
+            }
+            return word, matching(word, []string{TokensMode, ASTMode, EvalMode})
+        case ":time", ":type":
+            // Their argument is code
+        default:
+            return "", nil
+        }
+    }
+
+    word := before[strings.LastIndexFunc(before, func(r rune) bool { return !isIdentRune(r) })+1:]
+    if word == "" || unicode.IsDigit([]rune(word)[0]) {
+        return word, nil
+    }
+
+    return word, matching(word, append(token.Keywords(), s.Env.Names()...))
+}
+
+// The distinct candidates that start with prefix, sorted
+func matching(prefix string, candidates []string) []string {
+    seen := map[string]bool{}
+    matches := []string{}
+
+    for _, c := range candidates {
+        if strings.HasPrefix(c, prefix) && !seen[c] {
+            seen[c] = true
+            matches = append(matches, c)
+        }
+    }
+
+    sort.Strings(matches)
+    return matches
+}
+
+// Letters, digits and underscores, as in the lexer's identifiers
+func isIdentRune(r rune) bool {
+    return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,49 @@
+package repl
+
+import (
+    "io"
+    "reflect"
+    "testing"
+)
+
+func TestComplete(t *testing.T) {
+    s := NewSession(io.Discard)
+    s.Run("let counter = 0; let count = 1; let format = 2;")
+
+    tests := []struct {
+        before string
+        word string
+        matches []string
+    } {
+        {"co", "co", []string{"continue", "count", "counter"}},
+        {"let x = cou", "cou", []string{"count", "counter"}},
+        {"f", "f", []string{"false", "fn", "for", "format"}},
+        {"whi", "whi", []string{"while"}},
+        {"x + zz", "zz", []string{}},
+        {"f(", "", nil},
+        {"x + 1", "1", nil},
+        {":", ":", []string{":env", ":help", ":load", ":mode", ":reset", ":time", ":type"}},
+        {"  :t", ":t", []string{":time", ":type"}},
+        {":mode e", "e", []string{"eval"}},
+        {":mode ", "", []string{"ast", "eval", "tokens"}},
+        {":time cou", "cou", []string{"count", "counter"}},
+        {":load fo", "", nil},
+    }
+
+    for _, tt := range tests {
+        word, matches := s.Complete(tt.before)
+        if word != tt.word || !reflect.DeepEqual(matches, tt.matches) {
+            t.Errorf("%q: expected %q %q got %q %q", tt.before, tt.word, tt.matches, word, matches)
+        }
+    }
+}
+
+func TestCompleteWhileContinuing(t *testing.T) {
+    s := NewSession(io.Discard)
+    s.Feed("let f = fn() {")
+
+    // A colon here is code, not a command
+    if word, matches := s.Complete("  :re"); word != "re" || !reflect.DeepEqual(matches, []string{"return"}) {
+        t.Errorf("expected return got %q %q", word, matches)
+    }
+}
@@ -0,0 +1,378 @@
+package repl
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "unicode/utf8"
+)
+
+// Returned by ReadLine when the user presses Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// What a key press asks the editor to do
+type keyKind int
+
+const (
+    keyIgnored keyKind = iota
+    keyRune
+    keyEnter
+    keyTab
+    keyBackspace
+    keyDelete // Ctrl-D deletes too, or ends the input on an empty line
+    keyLeft
+    keyRight
+    keyUp
+    keyDown
+    keyHome
+    keyEnd
+    keyKillEnd
+    keyKillStart
+    keyKillWord
+    keyClear
+    keySearch
+    keyCancel
+    keyInterrupt
+    keyEOF
+)
+
+type key struct {
+    kind keyKind
+    r rune
+}
+
+var controlKeys = map[rune]keyKind{
+    '\r': keyEnter,
+    '\n': keyEnter,
+    '\t': keyTab,
+    127: keyBackspace,
+    'H' - '@': keyBackspace,
+    'A' - '@': keyHome,
+    'E' - '@': keyEnd,
+    'B' - '@': keyLeft,
+    'F' - '@': keyRight,
+    'P' - '@': keyUp,
+    'N' - '@': keyDown,
+    'K' - '@': keyKillEnd,
+    'U' - '@': keyKillStart,
+    'W' - '@': keyKillWord,
+    'L' - '@': keyClear,
+    'R' - '@': keySearch,
+    'G' - '@': keyCancel,
+    'C' - '@': keyInterrupt,
+    'D' - '@': keyEOF,
+}
+
+// The final bytes of the ANSI escape sequences for the cursor keys, and
+// the numbers of the ones ending in ~
+var escapeKeys = map[string]keyKind{
+    "A": keyUp,
+    "B": keyDown,
+    "C": keyRight,
+    "D": keyLeft,
+    "H": keyHome,
+    "F": keyEnd,
+    "1~": keyHome,
+    "7~": keyHome,
+    "4~": keyEnd,
+    "8~": keyEnd,
+    "3~": keyDelete,
+}
+
+// editor reads lines from a terminal in raw mode, with Emacs style
+// editing keys, history and completion. It only deals with bytes, putting
+// the terminal into raw mode is up to the caller.
+type editor struct {
+    in *bufio.Reader
+    out io.Writer
+    history *History
+    complete func(before string) (string, []string)
+
+    prompt string
+    buf []rune
+    cur int
+}
+
+func newEditor(in io.Reader, out io.Writer, history *History, complete func(string) (string, []string)) *editor {
+    return &editor{in: bufio.NewReader(in), out: out, history: history, complete: complete}
+}
+
+func (e *editor) readKey() (key, error) {
+    r, _, err := e.in.ReadRune()
+    if err != nil {
+        return key{}, err
+    }
+
+    if r != 27 {
+        if kind, ok := controlKeys[r]; ok {
+            return key{kind: kind}, nil
+        }
+        if r < ' ' {
+            return key{kind: keyIgnored}, nil
+        }
+        return key{kind: keyRune, r: r}, nil
+    }
+
+    // A lone Escape is ignored. Sequences arrive in one piece, so anything
+    // not yet buffered is not part of this one.
+    if e.in.Buffered() == 0 {
+        return key{kind: keyIgnored}, nil
+    }
+    // Alt with a key is ignored, but another Escape starts a new sequence
+    if next, _ := e.in.Peek(1); next[0] == 27 {
+        return key{kind: keyIgnored}, nil
+    }
+    if b, _ := e.in.ReadByte(); b != '[' && b != 'O' {
+        return key{kind: keyIgnored}, nil
+    }
+
+    var seq strings.Builder
+    for {
+        b, err := e.in.ReadByte()
+        if err != nil {
+            return key{}, err
+        }
+        seq.WriteByte(b)
+
+        if b >= 0x40 && b <= 0x7e {
+            return key{kind: escapeKeys[seq.String()]}, nil
+        }
+    }
+}
+
+// Redraws the line and puts the cursor back where it belongs
+func (e *editor) refresh() {
+    e.draw(e.prompt, e.buf, e.cur)
+}
+
+func (e *editor) draw(prompt string, buf []rune, cur int) {
+    var out strings.Builder
+    out.WriteString("\r" + prompt + string(buf) + "\x1b[K\r")
+
+    if col := utf8.RuneCountInString(prompt) + cur; col > 0 {
+        fmt.Fprintf(&out, "\x1b[%dC", col)
+    }
+    io.WriteString(e.out, out.String())
+}
+
+func (e *editor) setLine(line string) {
+    e.buf = []rune(line)
+    e.cur = len(e.buf)
+}
+
+func (e *editor) insert(text []rune) {
+    e.buf = append(e.buf[:e.cur], append(text, e.buf[e.cur:]...)...)
+    e.cur += len(text)
+}
+
+// ReadLine shows prompt and returns the line entered, without the newline.
+// It returns io.EOF for Ctrl-D on an empty line and errInterrupted for
+// Ctrl-C. Adding the line to the history is up to the caller.
+func (e *editor) ReadLine(prompt string) (string, error) {
+    e.prompt = prompt
+    e.buf = nil
+    e.cur = 0
+
+    index := len(e.history.Lines) // Position in the history, len for the new line
+    draft := ""                   // The new line while browsing the history
+
+    e.refresh()
+    var pending *key
+    for {
+        var k key
+        if pending != nil {
+            k, pending = *pending, nil
+        } else {
+            var err error
+            if k, err = e.readKey(); err != nil {
+                return "", err
+            }
+        }
+
+        switch k.kind {
+        case keyRune:
+            e.insert([]rune{k.r})
+
+        case keyEnter:
+            io.WriteString(e.out, "\r\n")
+            return string(e.buf), nil
+
+        case keyInterrupt:
+            io.WriteString(e.out, "^C\r\n")
+            return "", errInterrupted
+
+        case keyEOF:
+            if len(e.buf) == 0 {
+                io.WriteString(e.out, "\r\n")
+                return "", io.EOF
+            }
+            fallthrough
+        case keyDelete:
+            if e.cur < len(e.buf) {
+                e.buf = append(e.buf[:e.cur], e.buf[e.cur+1:]...)
+            }
+
+        case keyBackspace:
+            if e.cur > 0 {
+                e.buf = append(e.buf[:e.cur-1], e.buf[e.cur:]...)
+                e.cur -= 1
+            }
+
+        case keyLeft:
+            e.cur = max(e.cur-1, 0)
+        case keyRight:
+            e.cur = min(e.cur+1, len(e.buf))
+        case keyHome:
+            e.cur = 0
+        case keyEnd:
+            e.cur = len(e.buf)
+
+        case keyKillEnd:
+            e.buf = e.buf[:e.cur]
+        case keyKillStart:
+            e.buf = e.buf[e.cur:]
+            e.cur = 0
+        case keyKillWord:
+            start := e.cur
+            for start > 0 && e.buf[start-1] == ' ' {
+                start -= 1
+            }
+            for start > 0 && e.buf[start-1] != ' ' {
+                start -= 1
+            }
+            e.buf = append(e.buf[:start], e.buf[e.cur:]...)
+            e.cur = start
+
+        case keyUp:
+            if index > 0 {
+                if index == len(e.history.Lines) {
+                    draft = string(e.buf)
+                }
+                index -= 1
+                e.setLine(e.history.Lines[index])
+            }
+        case keyDown:
+            if index < len(e.history.Lines) {
+                index += 1
+                if index == len(e.history.Lines) {
+                    e.setLine(draft)
+                } else {
+                    e.setLine(e.history.Lines[index])
+                }
+            }
+
+        case keyTab:
+            e.completeWord()
+        case keyClear:
+            io.WriteString(e.out, "\x1b[H\x1b[2J")
+
+        case keySearch:
+            next, found, err := e.search()
+            if err != nil {
+                return "", err
+            }
+            if found >= 0 {
+                index = found
+            }
+            pending = next
+        }
+
+        e.refresh()
+    }
+}
+
+// Completes the word before the cursor as far as all candidates agree. If
+// that adds nothing, the candidates are listed below the line.
+func (e *editor) completeWord() {
+    word, matches := e.complete(string(e.buf[:e.cur]))
+    if len(matches) == 0 {
+        io.WriteString(e.out, "\a")
+        return
+    }
+
+    common := matches[0]
+    for _, m := range matches[1:] {
+        for !strings.HasPrefix(m, common) {
+            _, size := utf8.DecodeLastRuneInString(common)
+            common = common[:len(common)-size]
+        }
+    }
+
+    if len(common) > len(word) {
+        e.insert([]rune(common[len(word):]))
+        return
+    }
+
+    if len(matches) > 1 {
+        io.WriteString(e.out, "\r\n"+strings.Join(matches, "  ")+"\r\n")
+    }
+}
+
+// Runs a reverse incremental search through the history, started by
+// Ctrl-R. Typing refines the query and Ctrl-R again finds older matches.
+// Ctrl-G or Ctrl-C gives up, any other key takes the match into the line
+// and is returned to be handled as usual, along with the index of the
+// match or -1.
+func (e *editor) search() (*key, int, error) {
+    original, cur := e.buf, e.cur
+    query := []rune{}
+    found := -1
+
+    for {
+        prompt := "(reverse-i-search)`" + string(query) + "': "
+        if len(query) > 0 && found < 0 {
+            prompt = "(failed " + prompt[1:]
+        }
+
+        match := []rune{}
+        if found >= 0 {
+            match = []rune(e.history.Lines[found])
+        }
+        e.draw(prompt, match, len(match))
+
+        k, err := e.readKey()
+        if err != nil {
+            return nil, -1, err
+        }
+
+        switch k.kind {
+        case keyRune:
+            query = append(query, k.r)
+            from := found
+            if from < 0 {
+                from = len(e.history.Lines) - 1
+            }
+            found = e.history.Search(string(query), from)
+
+        case keyBackspace:
+            if len(query) > 0 {
+                query = query[:len(query)-1]
+                found = -1
+                if len(query) > 0 {
+                    found = e.history.Search(string(query), len(e.history.Lines)-1)
+                }
+            }
+
+        case keySearch:
+            if found > 0 {
+                if older := e.history.Search(string(query), found-1); older >= 0 {
+                    found = older
+                }
+            }
+
+        case keyCancel, keyInterrupt:
+            e.buf, e.cur = original, cur
+            return nil, -1, nil
+
+        default:
+            if found >= 0 {
+                e.setLine(e.history.Lines[found])
+            } else {
+                e.buf, e.cur = original, cur
+            }
+            return &k, found, nil
+        }
+    }
+}
@@ -0,0 +1,164 @@
+package repl
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// Types keys into an editor over the given history and returns the line
+// it reads along with what it wrote
+func typeKeys(history []string, keys string) (string, string, error) {
+    s := NewSession(io.Discard)
+    s.Run("let count = 1; let counter = 2;")
+
+    var out bytes.Buffer
+    e := newEditor(strings.NewReader(keys), &out, &History{Lines: history}, s.Complete)
+    line, err := e.ReadLine("> ")
+    return line, out.String(), err
+}
+
+// Like typeKeys, for keys that end with a line being entered
+func readLine(t *testing.T, history []string, keys string) (string, string) {
+    t.Helper()
+
+    line, out, err := typeKeys(history, keys)
+    if err != nil {
+        t.Fatalf("%q: ReadLine failed: %v", keys, err)
+    }
+    return line, out
+}
+
+func TestEditing(t *testing.T) {
+    tests := []struct {
+        keys string
+        expected string
+    } {
+        {"abc\r", "abc"},
+        {"abc\n", "abc"},
+        {"abc\x1b[D\x1b[DX\r", "aXbc"},
+        {"abc\x1bOD\x1bOCX\r", "abcX"},
+        {"abc\x01X\x05Y\r", "XabcY"},
+        {"abc\x1b[H\x1b[C\x1b[3~\x1b[F!\r", "ac!"},
+        {"abc\x7f\x08\r", "a"},
+        {"abc\x02\x02\x04\r", "ac"},
+        {"let foo bar\x17\r", "let foo "},
+        {"let foo  \x17\r", "let "},
+        {"abcd\x02\x02\x0b\r", "ab"},
+        {"abcd\x02\x02\x15\r", "cd"},
+        {"héllo\x1b[D\x1b[D\x1b[D\x7f\r", "hllo"},
+        {"a\x1b\x1b[1;5Cb\x07\r", "ab"},
+        {"whi\t\r", "while"},
+        {"coun\t\r", "count"},
+        {"x + co\tu\t\r", "x + count"},
+    }
+
+    for _, tt := range tests {
+        if line, _ := readLine(t, nil, tt.keys); line != tt.expected {
+            t.Errorf("%q: expected %q got %q", tt.keys, tt.expected, line)
+        }
+    }
+}
+
+func TestCompletionList(t *testing.T) {
+    _, out := readLine(t, nil, "co\t\r")
+    if !strings.Contains(out, "\r\ncontinue  count  counter\r\n") {
+        t.Errorf("candidates not listed in %q", out)
+    }
+
+    _, out = readLine(t, nil, "zz\t\r")
+    if !strings.Contains(out, "\a") {
+        t.Errorf("no bell in %q", out)
+    }
+}
+
+func TestEditorEnds(t *testing.T) {
+    tests := []struct {
+        keys string
+        err error
+    } {
+        {"\x04", io.EOF},
+        {"abc\x03", errInterrupted},
+        {"abc", io.EOF},
+    }
+
+    for _, tt := range tests {
+        line, _, err := typeKeys(nil, tt.keys)
+        if err != tt.err || line != "" {
+            t.Errorf("%q: expected %v got %q, %v", tt.keys, tt.err, line, err)
+        }
+    }
+}
+
+func TestHistoryKeys(t *testing.T) {
+    history := []string{"one", "two", "bone"}
+
+    tests := []struct {
+        keys string
+        expected string
+    } {
+        {"\x1b[A\r", "bone"},
+        {"\x1b[A\x1b[A\x1b[A\x1b[A\r", "one"},
+        {"new\x1b[A\x1b[A\x1b[B\x1b[B\r", "new"},
+        {"\x10\x10\x0e!\r", "bone!"},
+        {"\x12on\r", "bone"},
+        {"\x12on\x12\r", "one"},
+        {"\x12tw\x1b[D\x7f\r", "to"},
+        {"\x12on\x7f\x7f\x12\r", ""},
+        {"ab\x12zz\x07c\r", "abc"},
+        {"ab\x12zz\r", "ab"},
+        {"\x12one\x1b[A\r", "two"},
+    }
+
+    for _, tt := range tests {
+        if line, _ := readLine(t, history, tt.keys); line != tt.expected {
+            t.Errorf("%q: expected %q got %q", tt.keys, tt.expected, line)
+        }
+    }
+
+    _, out := readLine(t, history, "\x12x\r")
+    if !strings.Contains(out, "(failed reverse-i-search)`x': ") {
+        t.Errorf("failed search not shown in %q", out)
+    }
+}
+
+func TestHistoryFile(t *testing.T) {
+    filename := filepath.Join(t.TempDir(), "history")
+
+    h, err := LoadHistory(filename)
+    if err != nil || len(h.Lines) != 0 {
+        t.Fatalf("expected an empty history got %q, %v", h.Lines, err)
+    }
+
+    for _, line := range []string{"a", "", "b", "b", "  ", "a"} {
+        if err := h.Add(line); err != nil {
+            t.Fatalf("Add failed: %v", err)
+        }
+    }
+
+    h, err = LoadHistory(filename)
+    if err != nil || !reflect.DeepEqual(h.Lines, []string{"a", "b", "a"}) {
+        t.Fatalf("expected [a b a] got %q, %v", h.Lines, err)
+    }
+
+    var many strings.Builder
+    for i := 0; i < maxHistory+10; i++ {
+        fmt.Fprintf(&many, "%d\n", i)
+    }
+    os.WriteFile(filename, []byte(many.String()), 0o600)
+
+    h, err = LoadHistory(filename)
+    if err != nil || len(h.Lines) != maxHistory || h.Lines[0] != "10" {
+        t.Fatalf("expected the newest %d lines got %d, %v", maxHistory, len(h.Lines), err)
+    }
+
+    data, _ := os.ReadFile(filename)
+    if strings.Count(string(data), "\n") != maxHistory {
+        t.Errorf("history file was not cut down")
+    }
+}
@@ -0,0 +1,84 @@
+package repl
+
+import (
+    "bufio"
+    "os"
+    "strings"
+)
+
+// Lines older than this are forgotten
+const maxHistory = 1000
+
+// History holds the lines entered in this and earlier sessions, oldest
+// first. If it has a file, every new line is appended to it.
+type History struct {
+    Lines []string
+    filename string
+}
+
+// Loads the history kept in filename. A missing file is an empty history.
+// A file that has grown past maxHistory lines is cut down to the newest.
+func LoadHistory(filename string) (*History, error) {
+    h := &History{filename: filename}
+
+    f, err := os.Open(filename)
+    if os.IsNotExist(err) {
+        return h, nil
+    }
+    if err != nil {
+        return h, err
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        h.Lines = append(h.Lines, scanner.Text())
+    }
+    if err := scanner.Err(); err != nil {
+        return h, err
+    }
+
+    if len(h.Lines) > maxHistory {
+        h.Lines = h.Lines[len(h.Lines)-maxHistory:]
+        data := strings.Join(h.Lines, "\n") + "\n"
+        return h, os.WriteFile(filename, []byte(data), 0o600)
+    }
+    return h, nil
+}
+
+// Records line unless it is blank or repeats the previous one
+func (h *History) Add(line string) error {
+    if strings.TrimSpace(line) == "" || len(h.Lines) > 0 && h.Lines[len(h.Lines)-1] == line {
+        return nil
+    }
+
+    h.Lines = append(h.Lines, line)
+    if len(h.Lines) > maxHistory {
+        h.Lines = h.Lines[1:]
+    }
+
+    if h.filename == "" {
+        return nil
+    }
+
+    f, err := os.OpenFile(h.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+    if err != nil {
+        return err
+    }
+    _, err = f.WriteString(line + "\n")
+    if closeErr := f.Close(); err == nil {
+        err = closeErr
+    }
+    return err
+}
+
+// Searches backwards from index from for a line containing query, and
+// returns its index or -1
+func (h *History) Search(query string, from int) int {
+    for i := min(from, len(h.Lines)-1); i >= 0; i-- {
+        if strings.Contains(h.Lines[i], query) {
+            return i
+        }
+    }
+    return -1
+}
@@ -4,26 +4,310 @@ import (
     "bufio"
     "fmt"
     "io"
+    "monkeylang/ast"
+    "monkeylang/ast/astdump"
+    "monkeylang/evaluator"
     "monkeylang/lexer"
+    "monkeylang/object"
+    "monkeylang/parser"
     "monkeylang/token"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
 )
 
 const PROMPT = "> "
 
+// Shown while the input so far is incomplete, e.g. inside a function body
+const CONTINUE_PROMPT = ".. "
+
+// What the session does with a line that is not a meta-command
+const (
+    TokensMode = "tokens"
+    ASTMode = "ast"
+    EvalMode = "eval"
+)
+
+const help = `Enter Monkey code to run it in the current mode, or one of:
+  :mode [tokens|ast|eval]  show or switch the mode
+  :load file               evaluate a file in this session
+  :env                     list the bindings of this session
+  :reset                   forget all bindings
+  :time expr               evaluate expr and report how long it took
+  :type expr               evaluate expr and print the type of its value
+  :help                    show this message
+
+Input that ends inside brackets or after an operator continues on the
+next line. An empty line stops and reports what is missing.
+`
+
+// Session is the state of one console: its mode and the bindings made so
+// far. Everything is written to Out.
+type Session struct {
+    Out io.Writer
+    Mode string
+    Env *object.Environment
+
+    pending []string // Lines of input that do not parse on their own yet
+}
+
+func NewSession(out io.Writer) *Session {
+    return &Session{Out: out, Mode: EvalMode, Env: object.NewEnvironment()}
+}
+
+// Reads lines from in until it is exhausted and runs them all in one
+// session. On a terminal the lines can be edited, with history kept in
+// ~/.monkey_history and completion on Tab.
 func Start(in io.Reader, out io.Writer) {
+    s := NewSession(out)
+    if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+        s.interact(f)
+        return
+    }
+
     scanner := bufio.NewScanner(in)
     for {
-        fmt.Print(PROMPT)
+        fmt.Fprint(out, s.Prompt())
         scanned := scanner.Scan()
         if !scanned {
+            fmt.Fprintln(out)
             return
         }
 
-        line := scanner.Text()
-        l := lexer.New(line)
+        s.Feed(scanner.Text())
+    }
+}
+
+// Runs the session on a terminal until Ctrl-D. Ctrl-C drops the line
+// being edited along with any unfinished input before it.
+func (s *Session) interact(terminal *os.File) {
+    history := &History{}
+    if home, err := os.UserHomeDir(); err == nil {
+        loaded, err := LoadHistory(filepath.Join(home, ".monkey_history"))
+        if err != nil {
+            fmt.Fprintf(s.Out, "history is not saved: %v\n", err)
+            loaded.filename = ""
+        }
+        history = loaded
+    }
+
+    e := newEditor(terminal, s.Out, history, s.Complete)
+    for {
+        restore, err := makeRaw(terminal.Fd())
+        if err != nil {
+            fmt.Fprintln(s.Out, err)
+            return
+        }
+        line, err := e.ReadLine(s.Prompt())
+        restore()
+
+        switch {
+        case err == errInterrupted:
+            s.Cancel()
+            continue
+        case err != nil:
+            return
+        }
 
-        for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-            fmt.Printf("%+v\n", tok)
+        if err := history.Add(line); err != nil {
+            fmt.Fprintf(s.Out, "history is not saved: %v\n", err)
+            history.filename = ""
         }
+        s.Feed(line)
+    }
+}
+
+// The prompt for the next line, which tells whether it continues the
+// previous ones
+func (s *Session) Prompt() string {
+    if len(s.pending) > 0 {
+        return CONTINUE_PROMPT
+    }
+    return PROMPT
+}
+
+// Feed takes one line typed by the user. Code that ends too early, inside
+// an unclosed bracket or after an operator, is kept until later lines
+// complete it; an empty line gives up and reports what is wrong with it.
+// Everything else is passed to Run.
+func (s *Session) Feed(line string) {
+    if len(s.pending) == 0 && (s.Mode == TokensMode || strings.HasPrefix(strings.TrimSpace(line), ":")) {
+        s.Run(line)
+        return
+    }
+
+    if len(s.pending) > 0 && strings.TrimSpace(line) == "" {
+        s.Run(s.Cancel())
+        return
+    }
+
+    input := strings.Join(append(s.pending, line), "\n")
+    p := parser.New(lexer.New(input))
+    p.ParseProgram()
+
+    if p.Incomplete() {
+        s.pending = append(s.pending, line)
+        return
     }
+
+    s.pending = nil
+    s.Run(input)
+}
+
+// Cancel drops the lines waiting to be completed and returns them
+func (s *Session) Cancel() string {
+    input := strings.Join(s.pending, "\n")
+    s.pending = nil
+    return input
+}
+
+var metaCommands = map[string]func(s *Session, arg string){
+    "mode": (*Session).mode,
+    "load": (*Session).load,
+    "env": (*Session).env,
+    "reset": (*Session).reset,
+    "time": (*Session).timed,
+    "type": (*Session).typeOf,
+    "help": (*Session).help,
+}
+
+// Runs one line of input: a meta-command if it starts with a colon,
+// otherwise code for the current mode
+func (s *Session) Run(line string) {
+    trimmed := strings.TrimSpace(line)
+    if trimmed == "" {
+        return
+    }
+
+    if strings.HasPrefix(trimmed, ":") {
+        name, arg, _ := strings.Cut(trimmed[1:], " ")
+        command, ok := metaCommands[name]
+        if !ok {
+            fmt.Fprintf(s.Out, "unknown command :%s, try :help\n", name)
+            return
+        }
+        command(s, strings.TrimSpace(arg))
+        return
+    }
+
+    switch s.Mode {
+    case TokensMode:
+        s.printTokens(line)
+    case ASTMode:
+        if program := s.parse("", line); program != nil {
+            astdump.FprintSExpr(s.Out, program)
+        }
+    default:
+        if program := s.parse("", line); program != nil {
+            s.print(evaluator.Eval(program, s.Env))
+        }
+    }
+}
+
+// The lexer's errors, such as an unterminated string, follow the tokens
+func (s *Session) printTokens(line string) {
+    l := lexer.New(line)
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+        fmt.Fprintf(s.Out, "%-6s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+    }
+
+    for _, err := range l.Errors() {
+        fmt.Fprintln(s.Out, err)
+    }
+}
+
+// Returns nil after printing the errors if src does not parse
+func (s *Session) parse(filename, src string) *ast.Program {
+    p := parser.New(lexer.NewFile(filename, src))
+    program := p.ParseProgram()
+
+    if errors := p.ParseErrors(); len(errors) != 0 {
+        for _, e := range errors {
+            fmt.Fprintln(s.Out, e)
+        }
+        return nil
+    }
+    return program
+}
+
+// Statements such as let have no value, so nothing is printed for them
+func (s *Session) print(result object.Object) {
+    if result != nil {
+        fmt.Fprintln(s.Out, result.Inspect())
+    }
+}
+
+func (s *Session) mode(arg string) {
+    switch arg {
+    case "":
+        fmt.Fprintln(s.Out, s.Mode)
+    case TokensMode, ASTMode, EvalMode:
+        s.Mode = arg
+    default:
+        fmt.Fprintf(s.Out, "unknown mode %q, want tokens, ast or eval\n", arg)
+    }
+}
+
+func (s *Session) load(filename string) {
+    if filename == "" {
+        fmt.Fprintln(s.Out, "usage: :load file")
+        return
+    }
+
+    src, err := os.ReadFile(filename)
+    if err != nil {
+        fmt.Fprintln(s.Out, err)
+        return
+    }
+
+    if program := s.parse(filename, string(src)); program != nil {
+        s.print(evaluator.Eval(program, s.Env))
+    }
+}
+
+func (s *Session) env(arg string) {
+    for _, name := range s.Env.Names() {
+        val, _ := s.Env.Get(name)
+        fmt.Fprintf(s.Out, "%s = %s\n", name, val.Inspect())
+    }
+}
+
+func (s *Session) reset(arg string) {
+    s.Env = object.NewEnvironment()
+}
+
+func (s *Session) timed(arg string) {
+    program := s.parse("", arg)
+    if program == nil {
+        return
+    }
+
+    start := time.Now()
+    result := evaluator.Eval(program, s.Env)
+    elapsed := time.Since(start)
+
+    s.print(result)
+    fmt.Fprintf(s.Out, "took %s\n", elapsed)
+}
+
+func (s *Session) typeOf(arg string) {
+    program := s.parse("", arg)
+    if program == nil {
+        return
+    }
+
+    result := evaluator.Eval(program, s.Env)
+    switch {
+    case result == nil:
+        fmt.Fprintln(s.Out, "no value")
+    case result.Type() == object.ERROR_OBJ:
+        s.print(result)
+    default:
+        fmt.Fprintln(s.Out, result.Type())
+    }
+}
+
+func (s *Session) help(arg string) {
+    fmt.Fprint(s.Out, help)
 }
@@ -0,0 +1,138 @@
+package repl
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+// Runs every line in one session and returns what it wrote
+func run(lines ...string) string {
+    var out bytes.Buffer
+    s := NewSession(&out)
+    for _, line := range lines {
+        s.Run(line)
+    }
+    return out.String()
+}
+
+func TestEvalMode(t *testing.T) {
+    output := run("let a = 2;", "let f = fn(x) { x * a };", "f(21)", "a = 3", "f(1)", "b")
+
+    expected := "42\n3\n3\nERROR: identifier not found: b\n"
+    if output != expected {
+        t.Errorf("expected %q got %q", expected, output)
+    }
+}
+
+func TestModes(t *testing.T) {
+    tests := []struct {
+        lines []string
+        expected string
+    } {
+        {[]string{":mode"}, "eval\n"},
+        {[]string{":mode tokens", "let x", ":mode"}, "1:1    Let        \"let\"\n1:5    IDENT      \"x\"\ntokens\n"},
+        {[]string{":mode tokens", `"abc`}, "1:1    ILLEGAL    \"\\\"abc\"\n1:1: unterminated string literal\n"},
+        {[]string{":mode ast", "-a"}, "(Program\n  (ExpressionStatement\n    Expression: (PrefixExpression -\n      Right: (Identifier a))))\n"},
+        {[]string{":mode ast", "let = 1"}, "1:5: Expected IDENT , got = instead\n"},
+        {[]string{":mode lisp", ":mode"}, "unknown mode \"lisp\", want tokens, ast or eval\neval\n"},
+    }
+
+    for _, tt := range tests {
+        if output := run(tt.lines...); output != tt.expected {
+            t.Errorf("%q: expected %q got %q", tt.lines, tt.expected, output)
+        }
+    }
+}
+
+func TestMetaCommands(t *testing.T) {
+    tests := []struct {
+        lines []string
+        expected string
+    } {
+        {[]string{"let b = [1];", "let a = true;", ":env"}, "a = true\nb = [1]\n"},
+        {[]string{"let a = 1;", ":reset", ":env", "a"}, "ERROR: identifier not found: a\n"},
+        {[]string{":type 1.5", ":type fn() {}", ":type let x = 1", ":type y"}, "FLOAT\nFUNCTION\nno value\nERROR: identifier not found: y\n"},
+        {[]string{":frobnicate"}, "unknown command :frobnicate, try :help\n"},
+        {[]string{":load"}, "usage: :load file\n"},
+        {[]string{""}, ""},
+    }
+
+    for _, tt := range tests {
+        if output := run(tt.lines...); output != tt.expected {
+            t.Errorf("%q: expected %q got %q", tt.lines, tt.expected, output)
+        }
+    }
+}
+
+func TestTime(t *testing.T) {
+    output := run("let x = 20;", ":time x + 1")
+
+    if !regexp.MustCompile(`^21\ntook \S+s\n$`).MatchString(output) {
+        t.Errorf("unexpected output %q", output)
+    }
+}
+
+func TestLoad(t *testing.T) {
+    filename := filepath.Join(t.TempDir(), "lib.mk")
+    os.WriteFile(filename, []byte("let double = fn(x) { x * 2 };\ndouble(2)"), 0o644)
+
+    output := run(":load "+filename, "double(5)")
+    if expected := "4\n10\n"; output != expected {
+        t.Errorf("expected %q got %q", expected, output)
+    }
+
+    bad := filepath.Join(t.TempDir(), "bad.mk")
+    os.WriteFile(bad, []byte("let x 1;"), 0o644)
+
+    output = run(":load " + bad)
+    if expected := bad + ":1:7: Expected = , got INT instead\n"; output != expected {
+        t.Errorf("expected %q got %q", expected, output)
+    }
+}
+
+func TestStart(t *testing.T) {
+    var out bytes.Buffer
+    Start(strings.NewReader("let x = 1;\nx + 1\n"), &out)
+
+    if expected := "> > 2\n> \n"; out.String() != expected {
+        t.Errorf("expected %q got %q", expected, out.String())
+    }
+}
+
+// Feeds every line to one session and returns what it wrote, including the
+// prompts
+func feed(lines ...string) string {
+    var out bytes.Buffer
+    s := NewSession(&out)
+    for _, line := range lines {
+        out.WriteString(s.Prompt())
+        s.Feed(line)
+    }
+    out.WriteString(s.Prompt())
+    return out.String()
+}
+
+func TestMultiLineInput(t *testing.T) {
+    tests := []struct {
+        lines []string
+        expected string
+    } {
+        {[]string{"let add = fn(a, b) {", "  a +", "  b", "};", "add(1, 2)"}, "> .. .. .. > 3\n> "},
+        {[]string{"[1,", "2][1]"}, "> .. 2\n> "},
+        {[]string{"let s = `a", "b`;", ":type s"}, "> .. > STRING\n> "},
+        {[]string{"(1 + 2;"}, "> 1:7: Expected ) , got ; instead\n> "},
+        {[]string{"if (x) {", "", "1"}, "> .. 1:9: Expected } , got EOF instead\n> 1\n> "},
+        {[]string{"let x = (1 +", "2 2)", "x"}, "> .. 2:3: Expected ) , got INT instead\n> ERROR: identifier not found: x\n> "},
+        {[]string{":mode tokens", "(", ":mode eval"}, "> > 1:1    (          \"(\"\n> > "},
+    }
+
+    for _, tt := range tests {
+        if output := feed(tt.lines...); output != tt.expected {
+            t.Errorf("%q: expected %q got %q", tt.lines, tt.expected, output)
+        }
+    }
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package repl
+
+import "errors"
+
+// Elsewhere the console reads plain lines without editing
+func isTerminal(fd uintptr) bool {
+    return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+    return nil, errors.New("raw terminal mode is not supported")
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package repl
+
+import (
+    "syscall"
+    "unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+    t := &syscall.Termios{}
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(t)))
+    if errno != 0 {
+        return nil, errno
+    }
+    return t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t)))
+    if errno != 0 {
+        return errno
+    }
+    return nil
+}
+
+func isTerminal(fd uintptr) bool {
+    _, err := getTermios(fd)
+    return err == nil
+}
+
+// Switches the terminal to raw mode, where every key press is read as it
+// comes and nothing is echoed, and returns a function that switches back.
+// Output processing stays on, so a newline still starts a new line.
+func makeRaw(fd uintptr) (func(), error) {
+    old, err := getTermios(fd)
+    if err != nil {
+        return nil, err
+    }
+
+    raw := *old
+    raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+    raw.Cflag |= syscall.CS8
+    raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+    raw.Cc[syscall.VMIN] = 1
+    raw.Cc[syscall.VTIME] = 0
+
+    if err := setTermios(fd, &raw); err != nil {
+        return nil, err
+    }
+    return func() { setTermios(fd, old) }, nil
+}
@@ -1,9 +1,61 @@
 package token
+
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
+// Position describes a location in the source. Line and Column start at 1,
+// Offset is the byte offset starting at 0. A zero Position is not valid.
+type Position struct {
+    Filename string
+    Offset int
+    Line int
+    Column int
+}
+
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// Renders as file:line:column, dropping the file when it is unknown
+func (p Position) String() string {
+    if !p.IsValid() {
+        if p.Filename != "" {
+            return p.Filename
+        }
+        return "-"
+    }
+
+    if p.Filename != "" {
+        return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+    }
+    return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Comment is a `//`, `#` or `/* */` comment, Text includes the delimiters
+type Comment struct {
+    Text string
+    Pos Position
+    End Position
+}
+
+// Block comments may continue on the same line, line comments may not
+func (c Comment) IsBlock() bool {
+    return len(c.Text) >= 2 && c.Text[:2] == "/*"
+}
+
 type Token struct {
     Type TokenType
     Literal string
+    Pos Position // First character of the token
+    End Position // Just past the last character of the token
+
+    // Comments are not tokens of their own but trivia of their neighbours:
+    // Leading holds the comments before the token, Trailing the ones after
+    // it that start on the same line.
+    Leading []Comment
+    Trailing []Comment
 }
 
 var keywords = map[string] TokenType  {
@@ -14,6 +66,21 @@ var keywords = map[string] TokenType  {
     "return": Return, 
     "true": True, 
     "false": False, 
+    "while": While,
+    "for": For,
+    "in": In,
+    "break": Break,
+    "continue": Continue,
+}
+
+// The spellings of all keywords, sorted
+func Keywords() []string {
+    words := make([]string, 0, len(keywords))
+    for word := range keywords {
+        words = append(words, word)
+    }
+    sort.Strings(words)
+    return words
 }
 
 func LookupIdent(ident string) TokenType {
@@ -29,8 +96,15 @@ const (
     
     Ident = "IDENT"
     Int = "INT"
+    Float = "FLOAT"
+    String = "STRING"
 
     Assign = "="
+    PlusAssign = "+="
+    MinusAssign = "-="
+    AsteriskAssign = "*="
+    SlashAssign = "/="
+    PercentAssign = "%="
     Plus = "+"
     Minus = "-"
     Bang = "!"
@@ -41,13 +115,27 @@ const (
     Colon = ":"
     GT = ">"
     LT = "<"
+    GTE = ">="
+    LTE = "<="
     EqualTo= "=="
     NotEqualTo= "!="
+    And = "&&"
+    Or = "||"
+    Percent = "%"
+    Power = "**"
+    Ampersand = "&"
+    Pipe = "|"
+    Caret = "^"
+    Tilde = "~"
+    ShiftLeft = "<<"
+    ShiftRight = ">>"
 
     LParen = "("
     RParen = ")"
     LBrace = "{"
     RBrace = "}"
+    LBracket = "["
+    RBracket = "]"
 
 
     Function = "Function"
@@ -57,5 +145,10 @@ const (
     Return = "return"
     True = "true"
     False = "false"
+    While = "while"
+    For = "for"
+    In = "in"
+    Break = "break"
+    Continue = "continue"
 
 )